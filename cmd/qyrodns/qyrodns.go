@@ -1,20 +1,42 @@
 package main
 
 import (
+	"time"
+
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns"
 	"github.com/qyrocloud/qyrodns/internal/pkg/env"
 )
 
 func main() {
 	qyrodns.NewServer(&qyrodns.ServerConfig{
-		DNSHost:       env.GetOrDefault("DNS_HOST", "0.0.0.0"),
-		DNSPort:       env.GetOrDefault("DNS_PORT", "5300"),
-		AdminHost:     env.GetOrDefault("ADMIN_HOST", "0.0.0.0"),
-		AdminPort:     env.GetOrDefault("ADMIN_PORT", "5301"),
-		MongoEndpoint: env.GetOrDefault("MONGO_ENDPOINT", "mongodb://localhost:27017"),
-		MongoDatabase: env.GetOrDefault("MONGO_DB", "qyrodns"),
-		JwtSigningKey: env.GetOrDefault("JWT_SIGNING_KEY", "secret"),
-		JwtIssuer:     env.GetOrDefault("JWT_ISSUER", "qyrodns"),
-		JwtAudience:   env.GetOrDefault("JWT_AUDIENCE", "qyrodns"),
+		DNSHost:        env.GetOrDefault("DNS_HOST", "0.0.0.0"),
+		DNSPort:        env.GetOrDefault("DNS_PORT", "5300"),
+		AdminHost:      env.GetOrDefault("ADMIN_HOST", "0.0.0.0"),
+		AdminPort:      env.GetOrDefault("ADMIN_PORT", "5301"),
+		StorageBackend: env.GetOrDefault("STORAGE_BACKEND", "mongo"),
+		MongoEndpoint:  env.GetOrDefault("MONGO_ENDPOINT", "mongodb://localhost:27017"),
+		MongoDatabase:  env.GetOrDefault("MONGO_DB", "qyrodns"),
+		BoltPath:       env.GetOrDefault("BOLT_PATH", "qyrodns.db"),
+		JwtSigningKey:  env.GetOrDefault("JWT_SIGNING_KEY", "secret"),
+		JwtIssuer:      env.GetOrDefault("JWT_ISSUER", "qyrodns"),
+		JwtAudience:    env.GetOrDefault("JWT_AUDIENCE", "qyrodns"),
+		TrustedProxies: env.GetListOrDefault("TRUSTED_PROXIES", nil),
+
+		MongoAllowStandalone: env.GetBoolOrDefault("MONGO_ALLOW_STANDALONE", false),
+
+		JwtAlgorithm:           env.GetOrDefault("JWT_ALGORITHM", "HS256"),
+		JwtKeyRotationInterval: env.GetDurationOrDefault("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+
+		AdminAccessTokenTTL:  env.GetDurationOrDefault("ADMIN_ACCESS_TOKEN_TTL", 15*time.Minute),
+		AdminRefreshTokenTTL: env.GetDurationOrDefault("ADMIN_REFRESH_TOKEN_TTL", 7*24*time.Hour),
+
+		ApiKeySecretOverlap: env.GetDurationOrDefault("API_KEY_SECRET_OVERLAP", 0),
+
+		DNSSnapshotPath:     env.GetOrDefault("DNS_SNAPSHOT_PATH", ""),
+		DNSSnapshotInterval: env.GetDurationOrDefault("DNS_SNAPSHOT_INTERVAL", 30*time.Second),
+		DNSMaxStaleness:     env.GetDurationOrDefault("DNS_MAX_STALENESS", time.Hour),
+
+		TrashRetention:     env.GetDurationOrDefault("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: env.GetDurationOrDefault("TRASH_PURGE_INTERVAL", time.Minute),
 	}).Start()
 }
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+)
+
+// Authorizer authenticates admins and checks their roles allow what they ask
+// for, answering with 401 or 403 otherwise.
+type Authorizer struct {
+	authenticator *auth.Authenticator
+	service       *Service
+}
+
+func NewAuthorizer(authenticator *auth.Authenticator, service *Service) *Authorizer {
+	return &Authorizer{authenticator: authenticator, service: service}
+}
+
+// Authenticate returns the admin of the request.
+func (a *Authorizer) Authenticate(c *gin.Context, ctx context.Context) (*Admin, bool) {
+	admin, _, ok := a.authenticate(c, ctx)
+
+	return admin, ok
+}
+
+// authenticate returns the admin of the request and the token it presented,
+// checking the token has not been revoked since it was issued.
+func (a *Authorizer) authenticate(c *gin.Context, ctx context.Context) (*Admin, *auth.AuthenticatedAdmin, bool) {
+	aa, err := a.authenticator.ValidateAdminContext(c)
+
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+
+		return nil, nil, false
+	}
+
+	admin, err := a.service.Get(ctx, aa.ID)
+
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+
+		return nil, nil, false
+	}
+
+	_, active := admin.Session(aa.SessionID, time.Now())
+
+	if admin.TokenVersion != aa.TokenVersion || !active {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "token has been revoked",
+		})
+
+		return nil, nil, false
+	}
+
+	return admin, aa, true
+}
+
+// Authorize returns the admin of the request when it has the permission on
+// the namespace, or on every namespace when namespaceID is empty.
+func (a *Authorizer) Authorize(c *gin.Context, ctx context.Context, permission Permission, namespaceID string) (*Admin, bool) {
+	admin, ok := a.Authenticate(c, ctx)
+
+	if !ok {
+		return nil, false
+	}
+
+	if !admin.Allows(permission, namespaceID) {
+		Forbid(c)
+
+		return nil, false
+	}
+
+	return admin, true
+}
+
+// Forbid answers that the admin lacks the permission.
+func Forbid(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"success": false,
+		"message": "Permission denied",
+	})
+}
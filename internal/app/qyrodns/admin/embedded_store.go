@@ -0,0 +1,266 @@
+package admin
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EmbeddedStore struct {
+	admins *embedded.Collection[Admin]
+}
+
+func NewEmbeddedStore(db embedded.DB) *EmbeddedStore {
+	return &EmbeddedStore{admins: embedded.NewCollection[Admin](db, "admins")}
+}
+
+func (s *EmbeddedStore) Insert(ctx context.Context, admin *Admin) error {
+	return s.admins.WithTransaction(ctx, func(ctx context.Context) error {
+		usernameTaken, err := s.usernameTaken(ctx, admin.Username)
+
+		if err != nil {
+			return err
+		}
+
+		if usernameTaken {
+			return storage.ErrDuplicate
+		}
+
+		return s.admins.Put(ctx, admin.ID.Hex(), admin)
+	})
+}
+
+func (s *EmbeddedStore) Count(ctx context.Context) (int64, error) {
+	return s.admins.Count(ctx, embedded.All[Admin])
+}
+
+func (s *EmbeddedStore) List(ctx context.Context, request *pagination.Request) (*pagination.Page[Admin], error) {
+	admins, err := s.admins.Find(ctx, embedded.All[Admin])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(admins, request, pagination.Order{}, cursor), nil
+}
+
+func (s *EmbeddedStore) Get(ctx context.Context, id primitive.ObjectID) (*Admin, error) {
+	return s.admins.Get(ctx, id.Hex())
+}
+
+func (s *EmbeddedStore) GetByUsername(ctx context.Context, username string) (*Admin, error) {
+	admins, err := s.admins.Find(ctx, func(admin *Admin) bool {
+		return admin.Username == username
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(admins) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return admins[0], nil
+}
+
+func (s *EmbeddedStore) SetPassword(ctx context.Context, id primitive.ObjectID, password string) (*Admin, error) {
+	var admin *Admin
+
+	err := s.admins.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		admin, err = s.admins.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		admin.Password = password
+		admin.TokenVersion++
+		admin.Sessions = nil
+		admin.UpdatedAt = time.Now()
+
+		return s.admins.Put(ctx, id.Hex(), admin)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+func (s *EmbeddedStore) RevokeTokens(ctx context.Context, id primitive.ObjectID) error {
+	return s.update(ctx, id, func(admin *Admin) error {
+		admin.TokenVersion++
+		admin.Sessions = nil
+
+		return nil
+	})
+}
+
+func (s *EmbeddedStore) AddSession(ctx context.Context, id primitive.ObjectID, session *Session, now time.Time) error {
+	return s.update(ctx, id, func(admin *Admin) error {
+		sessions := make([]Session, 0, len(admin.Sessions)+1)
+
+		for _, existing := range admin.Sessions {
+			if now.Before(existing.ExpiresAt) {
+				sessions = append(sessions, existing)
+			}
+		}
+
+		admin.Sessions = append(sessions, *session)
+
+		return nil
+	})
+}
+
+func (s *EmbeddedStore) RefreshSession(ctx context.Context, id primitive.ObjectID, sessionID string, tokenHash string, newTokenHash string, expiresAt time.Time) error {
+	return s.update(ctx, id, func(admin *Admin) error {
+		for i := range admin.Sessions {
+			if admin.Sessions[i].ID == sessionID && admin.Sessions[i].TokenHash == tokenHash {
+				admin.Sessions[i].TokenHash = newTokenHash
+				admin.Sessions[i].ExpiresAt = expiresAt
+
+				return nil
+			}
+		}
+
+		return storage.ErrNotFound
+	})
+}
+
+func (s *EmbeddedStore) DeleteSession(ctx context.Context, id primitive.ObjectID, sessionID string) error {
+	return s.update(ctx, id, func(admin *Admin) error {
+		admin.Sessions = slices.DeleteFunc(admin.Sessions, func(session Session) bool {
+			return session.ID == sessionID
+		})
+
+		return nil
+	})
+}
+
+func (s *EmbeddedStore) SetRoles(ctx context.Context, id primitive.ObjectID, roles []RoleAssignment) (*Admin, error) {
+	var admin *Admin
+
+	err := s.admins.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		admin, err = s.admins.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		admin.Roles = roles
+		admin.UpdatedAt = time.Now()
+
+		return s.admins.Put(ctx, id.Hex(), admin)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+// LockOtherSuperadmins only counts, embedded transactions being serialized.
+func (s *EmbeddedStore) LockOtherSuperadmins(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	return s.admins.Count(ctx, func(admin *Admin) bool {
+		return admin.ID != id && admin.Superadmin()
+	})
+}
+
+func (s *EmbeddedStore) GrantSuperadminToRoleless(ctx context.Context) (int64, error) {
+	var count int64
+
+	err := s.admins.WithTransaction(ctx, func(ctx context.Context) error {
+		admins, err := s.admins.Find(ctx, func(admin *Admin) bool {
+			return len(admin.Roles) == 0
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, admin := range admins {
+			admin.Roles = []RoleAssignment{{Role: RoleSuperadmin}}
+
+			err := s.admins.Put(ctx, admin.ID.Hex(), admin)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		count = int64(len(admins))
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *EmbeddedStore) Delete(ctx context.Context, id primitive.ObjectID) (*Admin, error) {
+	var admin *Admin
+
+	err := s.admins.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		admin, err = s.admins.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		return s.admins.Delete(ctx, id.Hex())
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
+
+func (s *EmbeddedStore) usernameTaken(ctx context.Context, username string) (bool, error) {
+	count, err := s.admins.Count(ctx, func(admin *Admin) bool {
+		return admin.Username == username
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// update applies the change to the admin in a transaction.
+func (s *EmbeddedStore) update(ctx context.Context, id primitive.ObjectID, change func(admin *Admin) error) error {
+	return s.admins.WithTransaction(ctx, func(ctx context.Context) error {
+		admin, err := s.admins.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		err = change(admin)
+
+		if err != nil {
+			return err
+		}
+
+		return s.admins.Put(ctx, id.Hex(), admin)
+	})
+}
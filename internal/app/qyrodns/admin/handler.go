@@ -2,22 +2,23 @@ package admin
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
 type Handler struct {
-	router        *gin.Engine
-	authenticator *auth.Authenticator
-	service       *Service
+	router     *gin.Engine
+	authorizer *Authorizer
+	service    *Service
 }
 
-func NewHandler(router *gin.Engine, authenticator *auth.Authenticator, service *Service) *Handler {
-	return &Handler{router: router, authenticator: authenticator, service: service}
+func NewHandler(router *gin.Engine, authorizer *Authorizer, service *Service) *Handler {
+	return &Handler{router: router, authorizer: authorizer, service: service}
 }
 
 func (h *Handler) Register() {
@@ -74,14 +75,14 @@ func (h *Handler) Register() {
 		c.JSON(http.StatusOK, token)
 	})
 
-	h.router.GET("/api/v1/admins/current", func(c *gin.Context) {
+	h.router.POST("/api/v1/admins/token/refresh", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
+		var req RefreshRequest
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -89,10 +90,10 @@ func (h *Handler) Register() {
 			return
 		}
 
-		admin, err := h.service.Get(ctx, aa.ID)
+		token, err := h.service.Refresh(ctx, &req)
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -100,26 +101,23 @@ func (h *Handler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, admin)
+		c.JSON(http.StatusOK, token)
 	})
 
-	h.router.PUT("/api/v1/admins/current/password", func(c *gin.Context) {
+	h.router.POST("/api/v1/admins/logout", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		admin, aa, ok := h.authorizer.authenticate(c, ctx)
 
+		if !ok {
 			return
 		}
 
-		var req PasswordChangeRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
+		var req LogoutRequest
+
+		// The body is optional.
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -128,7 +126,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		admin, err := h.service.ChangePassword(ctx, aa.ID, &req)
+		err := h.service.Logout(ctx, admin, aa.SessionID, req.All)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -139,17 +137,26 @@ func (h *Handler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, admin)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "logged out successfully",
+		})
 	})
 
-	h.router.POST("/api/v1/admins", func(c *gin.Context) {
+	h.router.GET("/api/v1/admins/current", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authenticate(c, ctx)
+
+		if !ok {
+			return
+		}
+
+		admin, err := h.service.Get(ctx, aa.ID.Hex())
 
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -157,8 +164,20 @@ func (h *Handler) Register() {
 			return
 		}
 
-		var req AdditionRequest
+		c.JSON(http.StatusOK, admin)
+	})
+
+	h.router.PUT("/api/v1/admins/current/password", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
 
+		aa, ok := h.authorizer.Authenticate(c, ctx)
+
+		if !ok {
+			return
+		}
+
+		var req PasswordChangeRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
@@ -168,7 +187,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		admin, err := h.service.Add(ctx, &req, aa.ID)
+		admin, err := h.service.ChangePassword(ctx, aa.ID.Hex(), &req)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -182,26 +201,31 @@ func (h *Handler) Register() {
 		c.JSON(http.StatusOK, admin)
 	})
 
-	h.router.GET("/api/v1/admins", func(c *gin.Context) {
+	h.router.POST("/api/v1/admins", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, PermissionManage, "")
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		if !ok {
+			return
+		}
+
+		var req AdditionRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
+
 			return
 		}
 
-		page := c.DefaultQuery("page", "0")
-		size := c.DefaultQuery("size", "50")
-		pageInt, err := strconv.ParseInt(page, 10, 64)
+		admin, err := h.service.Add(ctx, &req, aa.ID.Hex())
 
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -209,7 +233,20 @@ func (h *Handler) Register() {
 			return
 		}
 
-		sizeInt, err := strconv.ParseInt(size, 10, 64)
+		c.JSON(http.StatusOK, admin)
+	})
+
+	h.router.GET("/api/v1/admins", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, PermissionRead, "")
+
+		if !ok {
+			return
+		}
+
+		request, err := pagination.FromQuery(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -220,7 +257,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		admins, err := h.service.List(ctx, pageInt, sizeInt)
+		admins, err := h.service.List(ctx, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -238,14 +275,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, PermissionRead, "")
 
+		if !ok {
 			return
 		}
 
@@ -269,20 +301,15 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		aa, ok := h.authorizer.Authorize(c, ctx, PermissionManage, "")
 
+		if !ok {
 			return
 		}
 
 		adminID := c.Param("adminID")
 
-		admin, err := h.service.Delete(ctx, adminID)
+		admin, err := h.service.Delete(ctx, adminID, aa.ID.Hex())
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -300,9 +327,17 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		_, ok := h.authorizer.Authorize(c, ctx, PermissionManage, "")
+
+		if !ok {
+			return
+		}
+
+		adminID := c.Param("adminID")
+		password, err := h.service.ResetPassword(ctx, adminID)
+
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -310,8 +345,30 @@ func (h *Handler) Register() {
 			return
 		}
 
-		adminID := c.Param("adminID")
-		password, err := h.service.ResetPassword(ctx, adminID)
+		c.JSON(http.StatusOK, password)
+	})
+	h.router.PUT("/api/v1/admins/:adminID/roles", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, PermissionManage, "")
+
+		if !ok {
+			return
+		}
+
+		var req RolesRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		admin, err := h.service.SetRoles(ctx, c.Param("adminID"), &req)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -322,6 +379,6 @@ func (h *Handler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, password)
+		c.JSON(http.StatusOK, admin)
 	})
 }
@@ -7,10 +7,13 @@ import (
 )
 
 type Admin struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Username  string             `bson:"username" json:"username"`
-	Password  string             `bson:"password" json:"-"`
-	CreatorID string             `bson:"creator_id" json:"creator_id"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Username     string             `bson:"username" json:"username"`
+	Password     string             `bson:"password" json:"-"`
+	Roles        []RoleAssignment   `bson:"roles" json:"roles"`
+	TokenVersion int64              `bson:"token_version" json:"-"`
+	Sessions     []Session          `bson:"sessions,omitempty" json:"-"`
+	CreatorID    string             `bson:"creator_id" json:"creator_id"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
 }
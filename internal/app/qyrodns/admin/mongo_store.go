@@ -0,0 +1,200 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewMongoStore(mongo *mongo.Collection) *MongoStore {
+	return &MongoStore{mongo: mongo}
+}
+
+func (s *MongoStore) Insert(ctx context.Context, admin *Admin) error {
+	_, err := s.mongo.InsertOne(ctx, admin)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return storage.ErrDuplicate
+	}
+
+	return err
+}
+
+func (s *MongoStore) Count(ctx context.Context) (int64, error) {
+	return s.mongo.CountDocuments(ctx, bson.M{})
+}
+
+func (s *MongoStore) List(ctx context.Context, request *pagination.Request) (*pagination.Page[Admin], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{}, request, pagination.Order{}, cursor)
+}
+
+func (s *MongoStore) Get(ctx context.Context, id primitive.ObjectID) (*Admin, error) {
+	result := s.mongo.FindOne(ctx, bson.M{"_id": id})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) GetByUsername(ctx context.Context, username string) (*Admin, error) {
+	result := s.mongo.FindOne(ctx, bson.M{"username": username})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) SetPassword(ctx context.Context, id primitive.ObjectID, password string) (*Admin, error) {
+	fields := bson.M{
+		"$set": bson.M{
+			"password":   password,
+			"updated_at": time.Now(),
+		},
+		"$inc":   bson.M{"token_version": 1},
+		"$unset": bson.M{"sessions": ""},
+	}
+
+	filter := bson.M{
+		"_id": id,
+	}
+
+	result := s.mongo.FindOneAndUpdate(ctx, filter, fields, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) RevokeTokens(ctx context.Context, id primitive.ObjectID) error {
+	return s.update(ctx, bson.M{"_id": id}, bson.M{
+		"$inc":   bson.M{"token_version": 1},
+		"$unset": bson.M{"sessions": ""},
+	})
+}
+
+func (s *MongoStore) AddSession(ctx context.Context, id primitive.ObjectID, session *Session, now time.Time) error {
+	err := s.update(ctx, bson.M{"_id": id}, bson.M{"$pull": bson.M{"sessions": bson.M{"expires_at": bson.M{"$lte": now}}}})
+
+	if err != nil {
+		return err
+	}
+
+	return s.update(ctx, bson.M{"_id": id}, bson.M{"$push": bson.M{"sessions": session}})
+}
+
+func (s *MongoStore) RefreshSession(ctx context.Context, id primitive.ObjectID, sessionID string, tokenHash string, newTokenHash string, expiresAt time.Time) error {
+	filter := bson.M{
+		"_id": id,
+		"sessions": bson.M{
+			"$elemMatch": bson.M{"id": sessionID, "token_hash": tokenHash},
+		},
+	}
+
+	return s.update(ctx, filter, bson.M{"$set": bson.M{
+		"sessions.$.token_hash": newTokenHash,
+		"sessions.$.expires_at": expiresAt,
+	}})
+}
+
+func (s *MongoStore) DeleteSession(ctx context.Context, id primitive.ObjectID, sessionID string) error {
+	return s.update(ctx, bson.M{"_id": id}, bson.M{"$pull": bson.M{"sessions": bson.M{"id": sessionID}}})
+}
+
+func (s *MongoStore) SetRoles(ctx context.Context, id primitive.ObjectID, roles []RoleAssignment) (*Admin, error) {
+	fields := bson.M{
+		"$set": bson.M{
+			"roles":      roles,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{"_id": id}, fields, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) LockOtherSuperadmins(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	result, err := s.mongo.UpdateMany(ctx, bson.M{
+		"_id": bson.M{"$ne": id},
+		"roles": bson.M{
+			"$elemMatch": bson.M{
+				"role":         RoleSuperadmin,
+				"namespace_id": bson.M{"$exists": false},
+			},
+		},
+	}, bson.M{
+		"$inc": bson.M{"superadmin_lock": 1},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.MatchedCount, nil
+}
+
+func (s *MongoStore) GrantSuperadminToRoleless(ctx context.Context) (int64, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"roles": nil},
+			bson.M{"roles": bson.M{"$size": 0}},
+		},
+	}
+
+	result, err := s.mongo.UpdateMany(ctx, filter, bson.M{"$set": bson.M{
+		"roles": []RoleAssignment{{Role: RoleSuperadmin}},
+	}})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+func (s *MongoStore) Delete(ctx context.Context, id primitive.ObjectID) (*Admin, error) {
+	filter := bson.M{"_id": id}
+
+	result := s.mongo.FindOneAndDelete(ctx, filter)
+
+	return s.decode(result)
+}
+
+// update applies the update to the admin matching the filter, failing with
+// storage.ErrNotFound when none does.
+func (s *MongoStore) update(ctx context.Context, filter bson.M, update bson.M) error {
+	result, err := s.mongo.UpdateOne(ctx, filter, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoStore) decode(result *mongo.SingleResult) (*Admin, error) {
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, storage.ErrNotFound
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	admin := &Admin{}
+
+	if err := result.Decode(admin); err != nil {
+		return nil, err
+	}
+
+	return admin, nil
+}
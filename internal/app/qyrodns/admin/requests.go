@@ -10,10 +10,23 @@ type TokenRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	All bool `json:"all"`
+}
+
 type PasswordChangeRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
 type AdditionRequest struct {
-	Username string `json:"username" binding:"required"`
+	Username string           `json:"username" binding:"required"`
+	Roles    []RoleAssignment `json:"roles"`
+}
+
+type RolesRequest struct {
+	Roles []RoleAssignment `json:"roles" binding:"required"`
 }
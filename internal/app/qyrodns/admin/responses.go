@@ -1,7 +1,12 @@
 package admin
 
+import "time"
+
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token                 string    `json:"token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 
 type PasswordResponse struct {
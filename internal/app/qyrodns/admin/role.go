@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Role string
+
+const (
+	RoleSuperadmin     Role = "superadmin"
+	RoleNamespaceAdmin Role = "namespace-admin"
+	RoleAuditor        Role = "auditor"
+)
+
+type Permission string
+
+const (
+	// PermissionRead lets an admin see namespaces, records, API keys and
+	// admins.
+	PermissionRead Permission = "read"
+	// PermissionWrite lets an admin change namespaces, their records and the
+	// accesses of API keys to them.
+	PermissionWrite Permission = "write"
+	// PermissionManage lets an admin manage admins and API keys.
+	PermissionManage Permission = "manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleSuperadmin:     {PermissionRead, PermissionWrite, PermissionManage},
+	RoleNamespaceAdmin: {PermissionRead, PermissionWrite},
+	RoleAuditor:        {PermissionRead},
+}
+
+// RoleAssignment gives an admin a role, on every namespace or, when
+// NamespaceID is set, on that namespace only.
+type RoleAssignment struct {
+	Role        Role   `bson:"role" json:"role"`
+	NamespaceID string `bson:"namespace_id,omitempty" json:"namespace_id,omitempty"`
+}
+
+func (r *RoleAssignment) grants(permission Permission) bool {
+	for _, granted := range rolePermissions[r.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *RoleAssignment) validate() error {
+	if _, ok := rolePermissions[r.Role]; !ok {
+		return fmt.Errorf("invalid role %s", r.Role)
+	}
+
+	if r.NamespaceID == "" {
+		return nil
+	}
+
+	if r.Role == RoleSuperadmin {
+		return fmt.Errorf("role %s cannot be limited to a namespace", r.Role)
+	}
+
+	if !primitive.IsValidObjectID(r.NamespaceID) {
+		return fmt.Errorf("invalid namespace id %s", r.NamespaceID)
+	}
+
+	return nil
+}
+
+// Allows reports whether the admin has the permission on the namespace, or on
+// every namespace when namespaceID is empty.
+func (a *Admin) Allows(permission Permission, namespaceID string) bool {
+	for _, role := range a.Roles {
+		if role.NamespaceID != "" && role.NamespaceID != namespaceID {
+			continue
+		}
+
+		if role.grants(permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NamespaceIDs returns the namespaces the admin has the permission on through
+// roles limited to them.
+func (a *Admin) NamespaceIDs(permission Permission) []string {
+	namespaceIDs := make([]string, 0)
+
+	for _, role := range a.Roles {
+		if role.NamespaceID != "" && role.grants(permission) {
+			namespaceIDs = append(namespaceIDs, role.NamespaceID)
+		}
+	}
+
+	return namespaceIDs
+}
+
+// Superadmin reports whether the admin is a superadmin of every namespace.
+func (a *Admin) Superadmin() bool {
+	for _, role := range a.Roles {
+		if role.Role == RoleSuperadmin && role.NamespaceID == "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -7,25 +7,26 @@ import (
 	"time"
 
 	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 	"github.com/qyrocloud/qyrodns/internal/pkg/secret"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	mongo         *mongo.Collection
-	authenticator *auth.Authenticator
+	store           Store
+	transactor      storage.Transactor
+	authenticator   *auth.Authenticator
+	refreshTokenTTL time.Duration
 }
 
-func NewService(mongo *mongo.Collection, authenticator *auth.Authenticator) *Service {
-	return &Service{mongo: mongo, authenticator: authenticator}
+func NewService(store Store, transactor storage.Transactor, authenticator *auth.Authenticator, refreshTokenTTL time.Duration) *Service {
+	return &Service{store: store, transactor: transactor, authenticator: authenticator, refreshTokenTTL: refreshTokenTTL}
 }
 
 func (s *Service) Init(ctx context.Context, request *InitRequest) (*Admin, error) {
-	count, err := s.mongo.CountDocuments(ctx, bson.M{})
+	count, err := s.store.Count(ctx)
 
 	if err != nil {
 		return nil, err
@@ -45,11 +46,12 @@ func (s *Service) Init(ctx context.Context, request *InitRequest) (*Admin, error
 		ID:        primitive.NewObjectID(),
 		Username:  request.Username,
 		Password:  string(hashedPassword),
+		Roles:     []RoleAssignment{{Role: RoleSuperadmin}},
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = s.mongo.InsertOne(ctx, admin)
+	err = s.store.Insert(ctx, admin)
 
 	if err != nil {
 		return nil, err
@@ -59,35 +61,130 @@ func (s *Service) Init(ctx context.Context, request *InitRequest) (*Admin, error
 }
 
 func (s *Service) GetToken(ctx context.Context, request *TokenRequest) (*TokenResponse, error) {
-	result := s.mongo.FindOne(ctx, bson.M{"username": request.Username})
+	admin, err := s.store.GetByUsername(ctx, request.Username)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("invalid username and password combination")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(request.Password))
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid username and password combination")
 	}
 
-	var admin Admin
+	refreshSecret, err := secret.Generate(32)
 
-	if err := result.Decode(&admin); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(request.Password))
+	now := time.Now()
+
+	session := &Session{
+		ID:        primitive.NewObjectID().Hex(),
+		TokenHash: hashRefreshToken(refreshSecret),
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+		CreatedAt: now,
+	}
+
+	err = s.store.AddSession(ctx, admin.ID, session, now)
 
 	if err != nil {
-		return nil, fmt.Errorf("invalid username and password combination")
+		return nil, err
+	}
+
+	return s.issueTokens(admin, session, refreshSecret)
+}
+
+// Refresh issues a new access token for the session of the refresh token,
+// replacing the refresh token with a new one.
+func (s *Service) Refresh(ctx context.Context, request *RefreshRequest) (*TokenResponse, error) {
+	adminID, sessionID, presented, err := parseRefreshToken(request.RefreshToken)
+
+	if err != nil {
+		return nil, err
+	}
+
+	admin, err := s.store.Get(ctx, adminID)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	session, ok := admin.Session(sessionID, now)
+
+	if !ok || !session.verify(presented) {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	refreshSecret, err := secret.Generate(32)
+
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := &Session{
+		ID:        session.ID,
+		TokenHash: hashRefreshToken(refreshSecret),
+		ExpiresAt: now.Add(s.refreshTokenTTL),
+		CreatedAt: session.CreatedAt,
+	}
+
+	err = s.store.RefreshSession(ctx, admin.ID, session.ID, session.TokenHash, refreshed.TokenHash, refreshed.ExpiresAt)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(admin, refreshed, refreshSecret)
+}
+
+// Logout ends the session, or every session of the admin when all is set,
+// revoking their tokens.
+func (s *Service) Logout(ctx context.Context, admin *Admin, sessionID string, all bool) error {
+	var err error
+
+	if all {
+		err = s.store.RevokeTokens(ctx, admin.ID)
+	} else {
+		err = s.store.DeleteSession(ctx, admin.ID, sessionID)
 	}
 
-	token, err := s.authenticator.GenerateAdminToken(admin.ID.Hex())
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("admin not found")
+	}
+
+	return err
+}
+
+func (s *Service) issueTokens(admin *Admin, session *Session, refreshSecret string) (*TokenResponse, error) {
+	token, expiresAt, err := s.authenticator.GenerateAdminToken(admin.ID.Hex(), session.ID, admin.TokenVersion)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &TokenResponse{Token: token}, nil
+	return &TokenResponse{
+		Token:                 token,
+		ExpiresAt:             expiresAt,
+		RefreshToken:          formatRefreshToken(admin.ID, session.ID, refreshSecret),
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
 }
 
 func (s *Service) Get(ctx context.Context, adminID string) (*Admin, error) {
@@ -97,15 +194,13 @@ func (s *Service) Get(ctx context.Context, adminID string) (*Admin, error) {
 		return nil, err
 	}
 
-	result := s.mongo.FindOne(ctx, bson.M{"_id": id})
+	admin, err := s.store.Get(ctx, id)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("admin not found")
 	}
 
-	admin := &Admin{}
-
-	if err := result.Decode(&admin); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -119,36 +214,19 @@ func (s *Service) ChangePassword(ctx context.Context, adminID string, request *P
 		return nil, err
 	}
 
-	fields := bson.M{
-		"$set": bson.M{
-			"password":   string(hashedPassword),
-			"updated_at": time.Now(),
-		},
-	}
-
 	id, err := primitive.ObjectIDFromHex(adminID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.M{
-		"_id": id,
-	}
-
-	result := s.mongo.FindOneAndUpdate(ctx, filter, fields, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	admin, err := s.store.SetPassword(ctx, id, string(hashedPassword))
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("admin not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
-	}
-
-	admin := &Admin{}
-
-	if err := result.Decode(&admin); err != nil {
+	if err != nil {
 		return nil, err
 	}
 
@@ -156,16 +234,12 @@ func (s *Service) ChangePassword(ctx context.Context, adminID string, request *P
 }
 
 func (s *Service) Add(ctx context.Context, request *AdditionRequest, creatorID string) (*PasswordResponse, error) {
-	usernameExists, err := s.usernameExists(ctx, request.Username)
+	roles, err := validateRoles(request.Roles)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if usernameExists {
-		return nil, fmt.Errorf("username %s is already taken", request.Username)
-	}
-
 	password, err := secret.Generate(16)
 
 	if err != nil {
@@ -182,12 +256,17 @@ func (s *Service) Add(ctx context.Context, request *AdditionRequest, creatorID s
 		ID:        primitive.NewObjectID(),
 		Username:  request.Username,
 		Password:  string(hashedPassword),
+		Roles:     roles,
 		CreatorID: creatorID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = s.mongo.InsertOne(ctx, admin)
+	err = s.store.Insert(ctx, admin)
+
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil, fmt.Errorf("username %s is already taken", request.Username)
+	}
 
 	if err != nil {
 		return nil, err
@@ -196,50 +275,92 @@ func (s *Service) Add(ctx context.Context, request *AdditionRequest, creatorID s
 	return &PasswordResponse{Admin: admin, Password: password}, nil
 }
 
-func (s *Service) Delete(ctx context.Context, adminID string) (*Admin, error) {
-	id, err := primitive.ObjectIDFromHex(adminID)
+// SetRoles replaces the roles of the admin. The last superadmin cannot give
+// up the role.
+func (s *Service) SetRoles(ctx context.Context, adminID string, request *RolesRequest) (*Admin, error) {
+	roles, err := validateRoles(request.Roles)
 
 	if err != nil {
 		return nil, err
 	}
 
-	filter := bson.M{"_id": id}
+	var admin *Admin
 
-	result := s.mongo.FindOneAndDelete(ctx, filter)
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.Get(ctx, adminID)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
-		return nil, fmt.Errorf("admin not found")
-	}
+		if err != nil {
+			return err
+		}
 
-	if result.Err() != nil {
-		return nil, result.Err()
-	}
+		updated := &Admin{Roles: roles}
+
+		if current.Superadmin() && !updated.Superadmin() {
+			err := s.checkNotLastSuperadmin(ctx, current.ID)
 
-	admin := &Admin{}
+			if err != nil {
+				return err
+			}
+		}
 
-	if err := result.Decode(&admin); err != nil {
+		admin, err = s.store.SetRoles(ctx, current.ID, roles)
+
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("admin not found")
+		}
+
+		return err
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
 	return admin, nil
 }
 
-func (s *Service) List(ctx context.Context, page int64, size int64) ([]*Admin, error) {
-	result, err := s.mongo.Find(ctx, bson.M{}, options.Find().SetSkip(page*size).SetLimit(size))
-
-	if err != nil {
-		return nil, err
+// Delete deletes the admin. Admins cannot delete themselves, and the last
+// superadmin cannot be deleted.
+func (s *Service) Delete(ctx context.Context, adminID string, deleterID string) (*Admin, error) {
+	if adminID == deleterID {
+		return nil, fmt.Errorf("admins cannot delete themselves")
 	}
 
-	admins := make([]*Admin, 0)
+	var admin *Admin
+
+	err := s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.Get(ctx, adminID)
+
+		if err != nil {
+			return err
+		}
+
+		if current.Superadmin() {
+			err := s.checkNotLastSuperadmin(ctx, current.ID)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		admin, err = s.store.Delete(ctx, current.ID)
+
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("admin not found")
+		}
 
-	err = result.All(ctx, &admins)
+		return err
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return admins, nil
+	return admin, nil
+}
+
+func (s *Service) List(ctx context.Context, request *pagination.Request) (*pagination.Page[Admin], error) {
+	return s.store.List(ctx, request)
 }
 
 func (s *Service) ResetPassword(ctx context.Context, adminID string) (*PasswordResponse, error) {
@@ -258,12 +379,34 @@ func (s *Service) ResetPassword(ctx context.Context, adminID string) (*PasswordR
 	return &PasswordResponse{Admin: admin, Password: password}, nil
 }
 
-func (s *Service) usernameExists(ctx context.Context, username string) (bool, error) {
-	count, err := s.mongo.CountDocuments(ctx, bson.M{"username": username})
+// checkNotLastSuperadmin fails unless another superadmin than the admin
+// remains. It must run in the transaction taking the role from the admin.
+func (s *Service) checkNotLastSuperadmin(ctx context.Context, id primitive.ObjectID) error {
+	count, err := s.store.LockOtherSuperadmins(ctx, id)
 
 	if err != nil {
-		return false, err
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("at least one superadmin must remain")
+	}
+
+	return nil
+}
+
+func validateRoles(roles []RoleAssignment) ([]RoleAssignment, error) {
+	validated := make([]RoleAssignment, 0, len(roles))
+
+	for _, role := range roles {
+		err := role.validate()
+
+		if err != nil {
+			return nil, err
+		}
+
+		validated = append(validated, role)
 	}
 
-	return count > 0, nil
+	return validated, nil
 }
@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session is a login of an admin, kept alive by its refresh token. The access
+// tokens issued for a session are revoked along with it, on logout, and along
+// with every other session of the admin when its token version is bumped, on
+// password changes.
+type Session struct {
+	ID        string    `bson:"id" json:"id"`
+	TokenHash string    `bson:"token_hash" json:"-"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// RefreshTokenPrefix starts the refresh tokens, which are made of the prefix,
+// the ID of the admin, the ID of the session and the secret itself:
+// qdnsr_<admin id>_<session id>_<secret>.
+const RefreshTokenPrefix = "qdnsr"
+
+func formatRefreshToken(adminID primitive.ObjectID, sessionID string, secret string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", RefreshTokenPrefix, adminID.Hex(), sessionID, secret)
+}
+
+// parseRefreshToken returns the ID of the admin, the ID of the session and
+// the secret of a refresh token.
+func parseRefreshToken(token string) (primitive.ObjectID, string, string, error) {
+	components := strings.Split(token, "_")
+
+	if len(components) != 4 || components[0] != RefreshTokenPrefix || components[3] == "" {
+		return primitive.NilObjectID, "", "", fmt.Errorf("invalid refresh token")
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(components[1])
+
+	if err != nil {
+		return primitive.NilObjectID, "", "", fmt.Errorf("invalid refresh token")
+	}
+
+	return adminID, components[2], components[3], nil
+}
+
+// hashRefreshToken hashes the secret of a refresh token. The secrets are long
+// and random enough not to need a salt.
+func hashRefreshToken(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Session returns the session of the admin with the ID while it lasts.
+func (a *Admin) Session(sessionID string, now time.Time) (*Session, bool) {
+	for i := range a.Sessions {
+		if a.Sessions[i].ID == sessionID && now.Before(a.Sessions[i].ExpiresAt) {
+			return &a.Sessions[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *Session) verify(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(s.TokenHash), []byte(hashRefreshToken(secret))) == 1
+}
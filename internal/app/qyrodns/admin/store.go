@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Store interface {
+	Insert(ctx context.Context, admin *Admin) error
+	Count(ctx context.Context) (int64, error)
+	List(ctx context.Context, request *pagination.Request) (*pagination.Page[Admin], error)
+	Get(ctx context.Context, id primitive.ObjectID) (*Admin, error)
+	GetByUsername(ctx context.Context, username string) (*Admin, error)
+	// SetPassword sets the password of the admin and revokes its tokens.
+	SetPassword(ctx context.Context, id primitive.ObjectID, password string) (*Admin, error)
+	// RevokeTokens bumps the token version of the admin and ends its sessions.
+	RevokeTokens(ctx context.Context, id primitive.ObjectID) error
+	// AddSession adds the session to the admin, dropping its expired ones.
+	AddSession(ctx context.Context, id primitive.ObjectID, session *Session, now time.Time) error
+	// RefreshSession replaces the refresh token hash and expiry of the session
+	// while its hash is still tokenHash.
+	RefreshSession(ctx context.Context, id primitive.ObjectID, sessionID string, tokenHash string, newTokenHash string, expiresAt time.Time) error
+	DeleteSession(ctx context.Context, id primitive.ObjectID, sessionID string) error
+	SetRoles(ctx context.Context, id primitive.ObjectID, roles []RoleAssignment) (*Admin, error)
+	// LockOtherSuperadmins counts the admins other than the given one with the
+	// superadmin role on every namespace. In a transaction, it also writes to
+	// them, so that concurrent transactions taking the role from different
+	// admins conflict instead of each counting the other.
+	LockOtherSuperadmins(ctx context.Context, id primitive.ObjectID) (int64, error)
+	// GrantSuperadminToRoleless makes superadmins of the admins without roles,
+	// which predate roles and could do everything.
+	GrantSuperadminToRoleless(ctx context.Context) (int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*Admin, error)
+}
+
+func cursor(admin *Admin) pagination.Cursor {
+	return pagination.Cursor{ID: admin.ID}
+}
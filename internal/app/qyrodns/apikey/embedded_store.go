@@ -0,0 +1,282 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	apiKeyCore "github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EmbeddedStore struct {
+	apiKeys       *embedded.Collection[apiKeyCore.ApiKey]
+	legacyApiKeys *embedded.Collection[legacyApiKey]
+}
+
+func NewEmbeddedStore(db embedded.DB) *EmbeddedStore {
+	return &EmbeddedStore{
+		apiKeys:       embedded.NewCollection[apiKeyCore.ApiKey](db, "api_keys"),
+		legacyApiKeys: embedded.NewCollection[legacyApiKey](db, "api_keys"),
+	}
+}
+
+func (s *EmbeddedStore) Insert(ctx context.Context, apiKey *apiKeyCore.ApiKey) error {
+	return s.apiKeys.WithTransaction(ctx, func(ctx context.Context) error {
+		nameTaken, err := s.nameTaken(ctx, apiKey.Name, apiKey.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if nameTaken {
+			return storage.ErrDuplicate
+		}
+
+		return s.apiKeys.Put(ctx, apiKey.ID.Hex(), apiKey)
+	})
+}
+
+func (s *EmbeddedStore) List(ctx context.Context, filter *ListFilter, request *pagination.Request) (*pagination.Page[apiKeyCore.ApiKey], error) {
+	now := time.Now()
+
+	apiKeys, err := s.apiKeys.Find(ctx, func(apiKey *apiKeyCore.ApiKey) bool {
+		if filter.UnusedFor > 0 {
+			unusedSince := now.Add(-filter.UnusedFor)
+
+			if apiKey.LastUsedAt != nil && !apiKey.LastUsedAt.Before(unusedSince) {
+				return false
+			}
+
+			if apiKey.LastUsedAt == nil && apiKey.CreatedAt.After(unusedSince) {
+				return false
+			}
+		}
+
+		if filter.ExpiringWithin > 0 {
+			return apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now.Add(filter.ExpiringWithin))
+		}
+
+		return true
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(apiKeys, request, pagination.Order{}, cursor), nil
+}
+
+func (s *EmbeddedStore) Get(ctx context.Context, id primitive.ObjectID) (*apiKeyCore.ApiKey, error) {
+	return s.apiKeys.Get(ctx, id.Hex())
+}
+
+func (s *EmbeddedStore) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*apiKeyCore.ApiKey, error) {
+	return s.apiKeys.Find(ctx, func(apiKey *apiKeyCore.ApiKey) bool {
+		return slices.Contains(ids, apiKey.ID)
+	})
+}
+
+func (s *EmbeddedStore) GetByLegacySecretHash(ctx context.Context, hash string) (*apiKeyCore.ApiKey, error) {
+	apiKeys, err := s.apiKeys.Find(ctx, func(apiKey *apiKeyCore.ApiKey) bool {
+		return apiKey.LegacySecretHash != "" && apiKey.LegacySecretHash == hash
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(apiKeys) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return apiKeys[0], nil
+}
+
+func (s *EmbeddedStore) Update(ctx context.Context, id primitive.ObjectID, request *UpdateRequest) (*apiKeyCore.ApiKey, error) {
+	return s.modify(ctx, id, func(ctx context.Context, apiKey *apiKeyCore.ApiKey) error {
+		if request.Name != "" {
+			nameTaken, err := s.nameTaken(ctx, request.Name, id)
+
+			if err != nil {
+				return err
+			}
+
+			if nameTaken {
+				return storage.ErrDuplicate
+			}
+
+			apiKey.Name = request.Name
+		}
+
+		if request.ExpiresAt != nil {
+			apiKey.ExpiresAt = request.ExpiresAt
+		}
+
+		if request.NeverExpires {
+			apiKey.ExpiresAt = nil
+		}
+
+		if request.Disabled != nil {
+			apiKey.Disabled = *request.Disabled
+		}
+
+		if request.AllowedCIDRs != nil {
+			apiKey.AllowedCIDRs = request.AllowedCIDRs
+		}
+
+		return nil
+	})
+}
+
+// SetLastUsed leaves the update time alone, as using an API key does not
+// change it.
+func (s *EmbeddedStore) SetLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time, ip string) error {
+	return s.apiKeys.WithTransaction(ctx, func(ctx context.Context) error {
+		apiKey, err := s.apiKeys.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		apiKey.LastUsedAt = &at
+		apiKey.LastUsedIP = ip
+
+		return s.apiKeys.Put(ctx, id.Hex(), apiKey)
+	})
+}
+
+func (s *EmbeddedStore) SetSecrets(ctx context.Context, id primitive.ObjectID, secrets *Secrets) error {
+	_, err := s.modify(ctx, id, func(ctx context.Context, apiKey *apiKeyCore.ApiKey) error {
+		apiKey.SecretHash = secrets.Hash
+		apiKey.SecretSalt = secrets.Salt
+		apiKey.PreviousSecretHash = secrets.PreviousHash
+		apiKey.PreviousSecretSalt = secrets.PreviousSalt
+		apiKey.PreviousSecretExpiresAt = secrets.PreviousExpiresAt
+		apiKey.LegacySecretHash = secrets.LegacyHash
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *EmbeddedStore) Delete(ctx context.Context, id primitive.ObjectID) (*apiKeyCore.ApiKey, error) {
+	var apiKey *apiKeyCore.ApiKey
+
+	err := s.apiKeys.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		apiKey, err = s.apiKeys.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		return s.apiKeys.Delete(ctx, id.Hex())
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (s *EmbeddedStore) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	_, err := s.apiKeys.Get(ctx, id.Hex())
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// HashLegacySecrets rewrites the API keys with a plaintext secret, which is
+// dropped as it is not a field of apiKeyCore.ApiKey.
+func (s *EmbeddedStore) HashLegacySecrets(ctx context.Context) (int64, error) {
+	var count int64
+
+	err := s.apiKeys.WithTransaction(ctx, func(ctx context.Context) error {
+		legacyApiKeys, err := s.legacyApiKeys.Find(ctx, func(apiKey *legacyApiKey) bool {
+			return apiKey.Secret != ""
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, legacy := range legacyApiKeys {
+			apiKey, err := s.apiKeys.Get(ctx, legacy.ID.Hex())
+
+			if err != nil {
+				return err
+			}
+
+			apiKey.LegacySecretHash = apiKeyCore.HashLegacySecret(legacy.Secret)
+
+			err = s.apiKeys.Put(ctx, legacy.ID.Hex(), apiKey)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		count = int64(len(legacyApiKeys))
+
+		return nil
+	})
+
+	return count, err
+}
+
+func (s *EmbeddedStore) modify(ctx context.Context, id primitive.ObjectID, change func(ctx context.Context, apiKey *apiKeyCore.ApiKey) error) (*apiKeyCore.ApiKey, error) {
+	var apiKey *apiKeyCore.ApiKey
+
+	err := s.apiKeys.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		apiKey, err = s.apiKeys.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		err = change(ctx, apiKey)
+
+		if err != nil {
+			return err
+		}
+
+		apiKey.UpdatedAt = time.Now()
+
+		return s.apiKeys.Put(ctx, id.Hex(), apiKey)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (s *EmbeddedStore) nameTaken(ctx context.Context, name string, excludedID primitive.ObjectID) (bool, error) {
+	count, err := s.apiKeys.Count(ctx, func(apiKey *apiKeyCore.ApiKey) bool {
+		return apiKey.Name == name && apiKey.ID != excludedID
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
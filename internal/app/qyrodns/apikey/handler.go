@@ -2,22 +2,24 @@ package apikey
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
 type Handler struct {
-	router        *gin.Engine
-	authenticator *auth.Authenticator
-	service       *Service
+	router     *gin.Engine
+	authorizer *admin.Authorizer
+	service    *Service
 }
 
-func NewHandler(router *gin.Engine, authenticator *auth.Authenticator, service *Service) *Handler {
-	return &Handler{router: router, authenticator: authenticator, service: service}
+func NewHandler(router *gin.Engine, authorizer *admin.Authorizer, service *Service) *Handler {
+	return &Handler{router: router, authorizer: authorizer, service: service}
 }
 
 func (h *Handler) Register() {
@@ -26,13 +28,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionManage, "")
 
+		if !ok {
 			return
 		}
 
@@ -47,7 +45,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		apiKey, err := h.service.Create(ctx, &req, aa.ID)
+		apiKey, err := h.service.Create(ctx, &req, aa.ID.Hex())
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -65,19 +63,13 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, "")
 
+		if !ok {
 			return
 		}
 
-		page := c.DefaultQuery("page", "0")
-		size := c.DefaultQuery("size", "50")
-		pageInt, err := strconv.ParseInt(page, 10, 64)
+		request, err := pagination.FromQuery(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -88,9 +80,9 @@ func (h *Handler) Register() {
 			return
 		}
 
-		sizeInt, err := strconv.ParseInt(size, 10, 64)
+		var filter ListFilter
 
-		if err != nil {
+		if err := c.ShouldBindQuery(&filter); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -99,7 +91,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		apiKeys, err := h.service.List(ctx, pageInt, sizeInt)
+		apiKeys, err := h.service.List(ctx, &filter, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -117,14 +109,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, "")
 
+		if !ok {
 			return
 		}
 
@@ -148,13 +135,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionManage, "")
 
+		if !ok {
 			return
 		}
 
@@ -188,13 +171,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionManage, "")
 
+		if !ok {
 			return
 		}
 
@@ -214,14 +193,23 @@ func (h *Handler) Register() {
 		c.JSON(http.StatusOK, apiKey)
 	})
 
-	h.router.GET("/api/v1/api-keys/:apiKeyID/secret", func(c *gin.Context) {
+	h.router.PUT("/api/v1/api-keys/:apiKeyID/secret", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionManage, "")
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		if !ok {
+			return
+		}
+
+		apiKeyID := c.Param("apiKeyID")
+
+		var req SecretRequest
+
+		// The body is optional.
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -229,9 +217,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		apiKeyID := c.Param("apiKeyID")
-
-		apiKeySecret, err := h.service.GetSecret(ctx, apiKeyID)
+		apiKeySecret, err := h.service.ResetSecret(ctx, apiKeyID, &req)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -245,24 +231,19 @@ func (h *Handler) Register() {
 		c.JSON(http.StatusOK, apiKeySecret)
 	})
 
-	h.router.PUT("/api/v1/api-keys/:apiKeyID/secret", func(c *gin.Context) {
+	h.router.DELETE("/api/v1/api-keys/:apiKeyID/secret/previous", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionManage, "")
 
+		if !ok {
 			return
 		}
 
 		apiKeyID := c.Param("apiKeyID")
 
-		apiKeySecret, err := h.service.ResetSecret(ctx, apiKeyID)
+		apiKey, err := h.service.EndSecretOverlap(ctx, apiKeyID)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -273,6 +254,6 @@ func (h *Handler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, apiKeySecret)
+		c.JSON(http.StatusOK, apiKey)
 	})
 }
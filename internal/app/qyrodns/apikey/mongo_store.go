@@ -0,0 +1,243 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apiKeyCore "github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewMongoStore(mongo *mongo.Collection) *MongoStore {
+	return &MongoStore{mongo: mongo}
+}
+
+func (s *MongoStore) Insert(ctx context.Context, apiKey *apiKeyCore.ApiKey) error {
+	_, err := s.mongo.InsertOne(ctx, apiKey)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return storage.ErrDuplicate
+	}
+
+	return err
+}
+
+func (s *MongoStore) List(ctx context.Context, filter *ListFilter, request *pagination.Request) (*pagination.Page[apiKeyCore.ApiKey], error) {
+	now := time.Now()
+	conditions := bson.A{}
+
+	if filter.UnusedFor > 0 {
+		unusedSince := now.Add(-filter.UnusedFor)
+
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$lt": unusedSince}},
+			bson.M{"last_used_at": nil, "created_at": bson.M{"$lte": unusedSince}},
+		}})
+	}
+
+	if filter.ExpiringWithin > 0 {
+		conditions = append(conditions, bson.M{"expires_at": bson.M{"$lte": now.Add(filter.ExpiringWithin)}})
+	}
+
+	query := bson.M{}
+
+	if len(conditions) > 0 {
+		query["$and"] = conditions
+	}
+
+	return pagination.Find(ctx, s.mongo, query, request, pagination.Order{}, cursor)
+}
+
+func (s *MongoStore) Get(ctx context.Context, id primitive.ObjectID) (*apiKeyCore.ApiKey, error) {
+	result := s.mongo.FindOne(ctx, bson.M{"_id": id})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*apiKeyCore.ApiKey, error) {
+	result, err := s.mongo.Find(ctx, bson.M{
+		"_id": bson.M{
+			"$in": ids,
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := make([]*apiKeyCore.ApiKey, 0)
+
+	err = result.All(ctx, &apiKeys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+func (s *MongoStore) GetByLegacySecretHash(ctx context.Context, hash string) (*apiKeyCore.ApiKey, error) {
+	result := s.mongo.FindOne(ctx, bson.M{
+		"legacy_secret_hash": hash,
+	})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) Update(ctx context.Context, id primitive.ObjectID, request *UpdateRequest) (*apiKeyCore.ApiKey, error) {
+	fields := bson.M{
+		"updated_at": time.Now(),
+	}
+
+	if request.Name != "" {
+		fields["name"] = request.Name
+	}
+
+	if request.ExpiresAt != nil {
+		fields["expires_at"] = request.ExpiresAt
+	}
+
+	if request.NeverExpires {
+		fields["expires_at"] = nil
+	}
+
+	if request.Disabled != nil {
+		fields["disabled"] = *request.Disabled
+	}
+
+	if request.AllowedCIDRs != nil {
+		fields["allowed_cidrs"] = request.AllowedCIDRs
+	}
+
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) SetSecrets(ctx context.Context, id primitive.ObjectID, secrets *Secrets) error {
+	fields := bson.M{
+		"secret_hash": secrets.Hash,
+		"secret_salt": secrets.Salt,
+		"updated_at":  time.Now(),
+	}
+
+	unset := bson.M{}
+
+	if secrets.PreviousExpiresAt != nil {
+		fields["previous_secret_hash"] = secrets.PreviousHash
+		fields["previous_secret_salt"] = secrets.PreviousSalt
+		fields["previous_secret_expires_at"] = secrets.PreviousExpiresAt
+	} else {
+		unset["previous_secret_hash"] = ""
+		unset["previous_secret_salt"] = ""
+		unset["previous_secret_expires_at"] = ""
+	}
+
+	if secrets.LegacyHash != "" {
+		fields["legacy_secret_hash"] = secrets.LegacyHash
+	} else {
+		unset["legacy_secret_hash"] = ""
+	}
+
+	update := bson.M{"$set": fields, "$unset": unset}
+
+	result, err := s.mongo.UpdateOne(ctx, bson.M{"_id": id}, update)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *MongoStore) SetLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time, ip string) error {
+	_, err := s.mongo.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"last_used_at": at,
+		"last_used_ip": ip,
+	}})
+
+	return err
+}
+
+func (s *MongoStore) Delete(ctx context.Context, id primitive.ObjectID) (*apiKeyCore.ApiKey, error) {
+	result := s.mongo.FindOneAndDelete(ctx, bson.M{"_id": id})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	count, err := s.mongo.CountDocuments(ctx, bson.M{"_id": id})
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *MongoStore) HashLegacySecrets(ctx context.Context) (int64, error) {
+	result, err := s.mongo.Find(ctx, bson.M{"secret": bson.M{"$exists": true}})
+
+	if err != nil {
+		return 0, err
+	}
+
+	apiKeys := make([]*legacyApiKey, 0)
+
+	err = result.All(ctx, &apiKeys)
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, apiKey := range apiKeys {
+		_, err := s.mongo.UpdateOne(ctx, bson.M{"_id": apiKey.ID}, bson.M{
+			"$set":   bson.M{"legacy_secret_hash": apiKeyCore.HashLegacySecret(apiKey.Secret)},
+			"$unset": bson.M{"secret": ""},
+		})
+
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(apiKeys)), nil
+}
+
+func (s *MongoStore) decode(result *mongo.SingleResult) (*apiKeyCore.ApiKey, error) {
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, storage.ErrNotFound
+	}
+
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		return nil, storage.ErrDuplicate
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	apiKey := &apiKeyCore.ApiKey{}
+
+	err := result.Decode(apiKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
@@ -1,9 +1,37 @@
 package apikey
 
+import "time"
+
 type CreationRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name         string     `json:"name" binding:"required"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	AllowedCIDRs []string   `json:"allowed_cidrs"`
 }
 
 type UpdateRequest struct {
-	Name string `json:"name"`
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// NeverExpires removes the expiry of the API key.
+	NeverExpires bool  `json:"never_expires"`
+	Disabled     *bool `json:"disabled"`
+	// AllowedCIDRs replaces the allowed CIDRs when set; an empty list allows
+	// any network.
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+}
+
+// SecretRequest sets how long the previous secret stays valid once replaced,
+// as a duration such as "24h". An empty overlap uses the configured default.
+type SecretRequest struct {
+	Overlap string `json:"overlap"`
+}
+
+// ListFilter narrows down API key listings to the keys to review. Zero
+// durations match every key.
+type ListFilter struct {
+	// UnusedFor matches the keys not used for this long, or never used and
+	// created at least this long ago.
+	UnusedFor time.Duration `form:"unused_for"`
+	// ExpiringWithin matches the keys expiring within this duration,
+	// including the expired ones.
+	ExpiringWithin time.Duration `form:"expiring_within"`
 }
@@ -1,5 +1,20 @@
 package apikey
 
-type SecretResponse struct {
+import (
+	"time"
+
+	apiKeyCore "github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+)
+
+// CreationResponse is the created API key along with its secret.
+type CreationResponse struct {
+	*apiKeyCore.ApiKey
 	Secret string `json:"secret"`
 }
+
+// SecretResponse is the new secret of the API key, along with the time until
+// which the previous one is still accepted, if at all.
+type SecretResponse struct {
+	Secret                  string     `json:"secret"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
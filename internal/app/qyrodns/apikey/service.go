@@ -7,72 +7,73 @@ import (
 	"time"
 
 	apiKeyCore "github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 	"github.com/qyrocloud/qyrodns/internal/pkg/secret"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Service struct {
-	mongo *mongo.Collection
+	store Store
+	// secretOverlap is how long previous secrets stay valid when a request
+	// does not say.
+	secretOverlap time.Duration
 }
 
-func NewService(mongo *mongo.Collection) *Service {
-	return &Service{mongo: mongo}
+func NewService(store Store, secretOverlap time.Duration) *Service {
+	return &Service{store: store, secretOverlap: secretOverlap}
 }
 
-func (s *Service) Create(ctx context.Context, request *CreationRequest, creatorID string) (*apiKeyCore.ApiKey, error) {
-	nameExists, err := s.nameExists(ctx, request.Name)
+// Create creates the API key, returning its secret, which is not shown
+// anymore afterwards.
+func (s *Service) Create(ctx context.Context, request *CreationRequest, creatorID string) (*CreationResponse, error) {
+	err := validateExpiry(request.ExpiresAt)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if nameExists {
-		return nil, fmt.Errorf("api key %s already exists", request.Name)
-	}
-
-	apiKeySecret, err := secret.Generate(128)
+	allowedCIDRs, err := apiKeyCore.NormalizeCIDRs(request.AllowedCIDRs)
 
 	if err != nil {
 		return nil, err
 	}
 
-	apiKey := &apiKeyCore.ApiKey{
-		ID:        primitive.NewObjectID(),
-		Name:      request.Name,
-		Secret:    apiKeySecret,
-		CreatorID: creatorID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+	id := primitive.NewObjectID()
 
-	_, err = s.mongo.InsertOne(ctx, apiKey)
+	apiKeySecret, hash, salt, err := newSecret(id)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return apiKey, nil
-}
-
-func (s *Service) List(ctx context.Context, page int64, size int64) ([]*apiKeyCore.ApiKey, error) {
-	apiKeys := make([]*apiKeyCore.ApiKey, 0)
+	apiKey := &apiKeyCore.ApiKey{
+		ID:           id,
+		Name:         request.Name,
+		SecretHash:   hash,
+		SecretSalt:   salt,
+		ExpiresAt:    request.ExpiresAt,
+		AllowedCIDRs: allowedCIDRs,
+		CreatorID:    creatorID,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
 
-	result, err := s.mongo.Find(ctx, bson.M{}, options.Find().SetSkip(page*size).SetLimit(size))
+	err = s.store.Insert(ctx, apiKey)
 
-	if err != nil {
-		return nil, err
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil, fmt.Errorf("api key %s already exists", request.Name)
 	}
 
-	err = result.All(ctx, &apiKeys)
-
 	if err != nil {
 		return nil, err
 	}
 
-	return apiKeys, nil
+	return &CreationResponse{ApiKey: apiKey, Secret: apiKeySecret}, nil
+}
+
+func (s *Service) List(ctx context.Context, filter *ListFilter, request *pagination.Request) (*pagination.Page[apiKeyCore.ApiKey], error) {
+	return s.store.List(ctx, filter, request)
 }
 
 func (s *Service) Get(ctx context.Context, apiKeyID string) (*apiKeyCore.ApiKey, error) {
@@ -82,20 +83,12 @@ func (s *Service) Get(ctx context.Context, apiKeyID string) (*apiKeyCore.ApiKey,
 		return nil, err
 	}
 
-	result := s.mongo.FindOne(ctx, bson.M{"_id": id})
+	apiKey, err := s.store.Get(ctx, id)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("api key not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
-	}
-
-	apiKey := &apiKeyCore.ApiKey{}
-
-	err = result.Decode(apiKey)
-
 	if err != nil {
 		return nil, err
 	}
@@ -104,49 +97,34 @@ func (s *Service) Get(ctx context.Context, apiKeyID string) (*apiKeyCore.ApiKey,
 }
 
 func (s *Service) Update(ctx context.Context, apiKeyID string, request *UpdateRequest) (*apiKeyCore.ApiKey, error) {
-	id, err := primitive.ObjectIDFromHex(apiKeyID)
+	err := validateExpiry(request.ExpiresAt)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fields := bson.M{
-		"updated_at": time.Now(),
-	}
+	request.AllowedCIDRs, err = apiKeyCore.NormalizeCIDRs(request.AllowedCIDRs)
 
-	if request.Name != "" {
-		count, err := s.mongo.CountDocuments(ctx, bson.M{
-			"_id": bson.M{
-				"$ne": id,
-			},
-			"name": request.Name,
-		})
-
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		if count > 0 {
-			return nil, fmt.Errorf("api key %s already exists", request.Name)
-		}
+	id, err := primitive.ObjectIDFromHex(apiKeyID)
 
-		fields["name"] = request.Name
+	if err != nil {
+		return nil, err
 	}
 
-	result := s.mongo.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": fields}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	apiKey, err := s.store.Update(ctx, id, request)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("api key not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil, fmt.Errorf("api key %s already exists", request.Name)
 	}
 
-	apiKey := &apiKeyCore.ApiKey{}
-
-	err = result.Decode(apiKey)
-
 	if err != nil {
 		return nil, err
 	}
@@ -161,82 +139,109 @@ func (s *Service) Delete(ctx context.Context, apiKeyID string) (*apiKeyCore.ApiK
 		return nil, err
 	}
 
-	result := s.mongo.FindOneAndDelete(ctx, bson.M{"_id": id})
+	apiKey, err := s.store.Delete(ctx, id)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("api key not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if err != nil {
+		return nil, err
 	}
 
-	apiKey := &apiKeyCore.ApiKey{}
+	return apiKey, nil
+}
 
-	err = result.Decode(apiKey)
+// ResetSecret replaces the secret of the API key, returning the new secret,
+// which is not shown anymore afterwards. The replaced secret stays valid for
+// the overlap of the request so that deployments can switch to the new one.
+func (s *Service) ResetSecret(ctx context.Context, apiKeyID string, request *SecretRequest) (*SecretResponse, error) {
+	overlap, err := s.overlap(request)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return apiKey, nil
-}
-
-func (s *Service) GetSecret(ctx context.Context, apiKeyID string) (*SecretResponse, error) {
 	apiKey, err := s.Get(ctx, apiKeyID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &SecretResponse{Secret: apiKey.Secret}, nil
-}
-
-func (s *Service) ResetSecret(ctx context.Context, apiKeyID string) (*SecretResponse, error) {
-	apiKeySecret, err := secret.Generate(128)
+	apiKeySecret, hash, salt, err := newSecret(apiKey.ID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := primitive.ObjectIDFromHex(apiKeyID)
+	secrets := &Secrets{Hash: hash, Salt: salt}
+
+	if overlap > 0 {
+		previousExpiresAt := time.Now().Add(overlap)
+
+		secrets.PreviousHash = apiKey.SecretHash
+		secrets.PreviousSalt = apiKey.SecretSalt
+		secrets.PreviousExpiresAt = &previousExpiresAt
+
+		// A legacy secret is only the previous secret until its first
+		// replacement.
+		if apiKey.SecretHash == "" {
+			secrets.LegacyHash = apiKey.LegacySecretHash
+		}
+	}
+
+	err = s.store.SetSecrets(ctx, apiKey.ID, secrets)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("api key not found")
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	fields := bson.M{
-		"secret":     apiKeySecret,
-		"updated_at": time.Now(),
-	}
+	return &SecretResponse{Secret: apiKeySecret, PreviousSecretExpiresAt: secrets.PreviousExpiresAt}, nil
+}
 
-	result, err := s.mongo.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+// EndSecretOverlap stops accepting the previous secret of the API key before
+// the end of its overlap.
+func (s *Service) EndSecretOverlap(ctx context.Context, apiKeyID string) (*apiKeyCore.ApiKey, error) {
+	apiKey, err := s.Get(ctx, apiKeyID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if result.MatchedCount == 0 {
-		return nil, fmt.Errorf("api key not found")
+	if !apiKey.InOverlap(time.Now()) {
+		return nil, fmt.Errorf("api key has no previous secret")
 	}
 
-	return &SecretResponse{Secret: apiKeySecret}, nil
-}
+	err = s.store.SetSecrets(ctx, apiKey.ID, &Secrets{Hash: apiKey.SecretHash, Salt: apiKey.SecretSalt})
 
-func (s *Service) Exists(ctx context.Context, apiKeyID string) (bool, error) {
-	id, err := primitive.ObjectIDFromHex(apiKeyID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("api key not found")
+	}
 
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	count, err := s.mongo.CountDocuments(ctx, bson.M{"_id": id})
+	apiKey.PreviousSecretHash = ""
+	apiKey.PreviousSecretSalt = ""
+	apiKey.PreviousSecretExpiresAt = nil
+	apiKey.LegacySecretHash = ""
+
+	return apiKey, nil
+}
+
+func (s *Service) Exists(ctx context.Context, apiKeyID string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(apiKeyID)
 
 	if err != nil {
 		return false, err
 	}
 
-	return count > 0, nil
+	return s.store.Exists(ctx, id)
 }
 
 func (s *Service) GetByIDs(ctx context.Context, apiKeyIDs []string) ([]*apiKeyCore.ApiKey, error) {
@@ -252,33 +257,50 @@ func (s *Service) GetByIDs(ctx context.Context, apiKeyIDs []string) ([]*apiKeyCo
 		ids[i] = id
 	}
 
-	result, err := s.mongo.Find(ctx, bson.M{
-		"_id": bson.M{
-			"$in": ids,
-		},
-	})
+	return s.store.GetByIDs(ctx, ids)
+}
+
+// newSecret generates a secret for the API key, returning the secret to
+// present along with its salted hash and the salt.
+func newSecret(id primitive.ObjectID) (string, string, string, error) {
+	apiKeySecret, err := secret.Generate(64)
 
 	if err != nil {
-		return nil, err
+		return "", "", "", err
 	}
 
-	apiKeys := make([]*apiKeyCore.ApiKey, 0)
-
-	err = result.All(ctx, &apiKeys)
+	salt, err := secret.Generate(32)
 
 	if err != nil {
-		return nil, err
+		return "", "", "", err
 	}
 
-	return apiKeys, nil
+	return apiKeyCore.FormatSecret(id, apiKeySecret), apiKeyCore.HashSecret(salt, apiKeySecret), salt, nil
 }
 
-func (s *Service) nameExists(ctx context.Context, name string) (bool, error) {
-	count, err := s.mongo.CountDocuments(ctx, bson.M{"name": name})
+// overlap returns how long the previous secret stays valid for the request.
+func (s *Service) overlap(request *SecretRequest) (time.Duration, error) {
+	if request.Overlap == "" {
+		return s.secretOverlap, nil
+	}
+
+	overlap, err := time.ParseDuration(request.Overlap)
 
 	if err != nil {
-		return false, err
+		return 0, fmt.Errorf("invalid overlap: %w", err)
+	}
+
+	if overlap < 0 {
+		return 0, fmt.Errorf("overlap must not be negative")
+	}
+
+	return overlap, nil
+}
+
+func validateExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future")
 	}
 
-	return count > 0, nil
+	return nil
 }
@@ -0,0 +1,49 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	apiKeyCore "github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Store interface {
+	Insert(ctx context.Context, apiKey *apiKeyCore.ApiKey) error
+	List(ctx context.Context, filter *ListFilter, request *pagination.Request) (*pagination.Page[apiKeyCore.ApiKey], error)
+	Get(ctx context.Context, id primitive.ObjectID) (*apiKeyCore.ApiKey, error)
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*apiKeyCore.ApiKey, error)
+	GetByLegacySecretHash(ctx context.Context, hash string) (*apiKeyCore.ApiKey, error)
+	Update(ctx context.Context, id primitive.ObjectID, request *UpdateRequest) (*apiKeyCore.ApiKey, error)
+	// SetSecrets replaces all the secrets of the API key.
+	SetSecrets(ctx context.Context, id primitive.ObjectID, secrets *Secrets) error
+	SetLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time, ip string) error
+	Delete(ctx context.Context, id primitive.ObjectID) (*apiKeyCore.ApiKey, error)
+	Exists(ctx context.Context, id primitive.ObjectID) (bool, error)
+	// HashLegacySecrets replaces the plaintext secrets of the API keys created
+	// before secrets were hashed with their legacy hash.
+	HashLegacySecrets(ctx context.Context) (int64, error)
+}
+
+// Secrets are the secrets an API key accepts, as stored in its
+// SecretHash, SecretSalt, PreviousSecret and LegacySecretHash fields.
+type Secrets struct {
+	Hash              string
+	Salt              string
+	PreviousHash      string
+	PreviousSalt      string
+	PreviousExpiresAt *time.Time
+	LegacyHash        string
+}
+
+// legacyApiKey reads the plaintext secret of an API key created before
+// secrets were hashed.
+type legacyApiKey struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	Secret string             `bson:"secret"`
+}
+
+func cursor(apiKey *apiKeyCore.ApiKey) pagination.Cursor {
+	return pagination.Cursor{ID: apiKey.ID}
+}
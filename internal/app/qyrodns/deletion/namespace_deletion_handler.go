@@ -6,46 +6,49 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
-	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/pkg/etag"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
+// NamespaceDeletionHandler moves namespaces to the trash and back. Trashed
+// namespaces are permanently deleted by the Purger once their retention
+// period expires.
 type NamespaceDeletionHandler struct {
-	router              *gin.Engine
-	authenticator       *auth.Authenticator
-	namespaceService    *namespace.Service
-	apiKeyAccessService *namespace.ApiKeyAccessService
-	recordService       *dns.RecordService
+	router           *gin.Engine
+	authorizer       *admin.Authorizer
+	namespaceService *namespace.Service
 }
 
-func NewNamespaceDeletionHandler(router *gin.Engine, authenticator *auth.Authenticator, namespaceService *namespace.Service, apiKeyAccessService *namespace.ApiKeyAccessService, recordService *dns.RecordService) *NamespaceDeletionHandler {
-	return &NamespaceDeletionHandler{router: router, authenticator: authenticator, namespaceService: namespaceService, apiKeyAccessService: apiKeyAccessService, recordService: recordService}
+func NewNamespaceDeletionHandler(router *gin.Engine, authorizer *admin.Authorizer, namespaceService *namespace.Service) *NamespaceDeletionHandler {
+	return &NamespaceDeletionHandler{router: router, authorizer: authorizer, namespaceService: namespaceService}
 }
 
 func (h *NamespaceDeletionHandler) Register() {
 
 	h.router.DELETE("/api/v1/namespaces/:namespaceID", func(c *gin.Context) {
+		h.changeStatus(c, h.namespaceService.Trash)
+	})
+
+	h.router.POST("/api/v1/namespaces/:namespaceID/restore", func(c *gin.Context) {
+		h.changeStatus(c, h.namespaceService.Restore)
+	})
+
+	h.router.GET("/api/v1/namespaces/trash", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, "")
 
+		if !ok {
 			return
 		}
 
-		namespaceID := c.Param("namespaceID")
-
-		namespaceDetails, err := h.namespaceService.Delete(ctx, namespaceID)
+		request, err := pagination.FromQuery(c)
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -53,7 +56,7 @@ func (h *NamespaceDeletionHandler) Register() {
 			return
 		}
 
-		err = h.apiKeyAccessService.DeleteByNamespaceID(ctx, namespaceID)
+		namespaces, err := h.namespaceService.ListTrash(ctx, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -64,17 +67,42 @@ func (h *NamespaceDeletionHandler) Register() {
 			return
 		}
 
-		err = h.recordService.DeleteByNamespaceID(ctx, namespaceID)
+		c.JSON(http.StatusOK, namespaces)
+	})
+}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+func (h *NamespaceDeletionHandler) changeStatus(c *gin.Context, change func(ctx context.Context, namespaceID string, ifMatch *int64) (*namespace.Namespace, error)) {
+	ctx, cancel := context.WithTimeout(c, time.Second*5)
+	defer cancel()
 
-			return
-		}
+	_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
 
-		c.JSON(http.StatusOK, namespaceDetails)
-	})
+	if !ok {
+		return
+	}
+
+	ifMatch, err := etag.IfMatch(c)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+
+		return
+	}
+
+	namespaceDetails, err := change(ctx, c.Param("namespaceID"), ifMatch)
+
+	if err != nil {
+		c.JSON(etag.Status(err), gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+
+		return
+	}
+
+	etag.Set(c, namespaceDetails.Version)
+	c.JSON(http.StatusOK, namespaceDetails)
 }
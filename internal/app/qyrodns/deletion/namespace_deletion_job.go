@@ -0,0 +1,85 @@
+package deletion
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+)
+
+// NamespaceDeletionJob removes the API key accesses and records of namespaces
+// marked as deleting, then the namespaces themselves. Every step can be
+// repeated, so a deletion interrupted midway is finished by the next run.
+type NamespaceDeletionJob struct {
+	namespaceService    *namespace.Service
+	apiKeyAccessService *namespace.ApiKeyAccessService
+	recordService       *dns.RecordService
+	interval            time.Duration
+}
+
+func NewNamespaceDeletionJob(namespaceService *namespace.Service, apiKeyAccessService *namespace.ApiKeyAccessService, recordService *dns.RecordService, interval time.Duration) *NamespaceDeletionJob {
+	return &NamespaceDeletionJob{namespaceService: namespaceService, apiKeyAccessService: apiKeyAccessService, recordService: recordService, interval: interval}
+}
+
+// Complete cleans up a namespace already marked as deleting.
+func (j *NamespaceDeletionJob) Complete(ctx context.Context, namespaceID string) error {
+	err := j.apiKeyAccessService.DeleteByNamespaceID(ctx, namespaceID)
+
+	if err != nil {
+		return err
+	}
+
+	err = j.recordService.DeleteByNamespaceID(ctx, namespaceID)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = j.namespaceService.Delete(ctx, namespaceID)
+
+	return err
+}
+
+// Resume completes every pending namespace deletion.
+func (j *NamespaceDeletionJob) Resume(ctx context.Context) error {
+	namespaces, err := j.namespaceService.ListDeleting(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range namespaces {
+		err = j.Complete(ctx, namespace.ID.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("completed deletion of namespace %s", namespace.Name)
+	}
+
+	return nil
+}
+
+// Run resumes pending deletions right away and then at every interval until
+// the context is done.
+func (j *NamespaceDeletionJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		err := j.Resume(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error while resuming namespace deletions: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
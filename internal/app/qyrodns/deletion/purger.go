@@ -0,0 +1,100 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+)
+
+// Purger permanently deletes the namespaces and records that stayed in the
+// trash longer than the retention period.
+type Purger struct {
+	namespaceService *namespace.Service
+	recordService    *dns.RecordService
+	job              *NamespaceDeletionJob
+	retention        time.Duration
+	interval         time.Duration
+}
+
+func NewPurger(namespaceService *namespace.Service, recordService *dns.RecordService, job *NamespaceDeletionJob, retention time.Duration, interval time.Duration) (*Purger, error) {
+	if retention < 0 {
+		return nil, fmt.Errorf("trash retention must not be negative")
+	}
+
+	if interval <= 0 {
+		return nil, fmt.Errorf("trash purge interval must be positive")
+	}
+
+	return &Purger{namespaceService: namespaceService, recordService: recordService, job: job, retention: retention, interval: interval}, nil
+}
+
+func (p *Purger) Purge(ctx context.Context) error {
+	deletedBefore := time.Now().Add(-p.retention)
+
+	namespaces, err := p.namespaceService.ListExpired(ctx, deletedBefore)
+
+	if err != nil {
+		return err
+	}
+
+	for _, expired := range namespaces {
+		_, err = p.namespaceService.MarkDeleting(ctx, expired)
+
+		if errors.Is(err, storage.ErrConflict) {
+			// Restored or changed since it was listed; the next purge will
+			// look at it again.
+			log.Printf("namespace %s changed since it expired, not purging it", expired.Name)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = p.job.Complete(ctx, expired.ID.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("purged namespace %s", expired.Name)
+	}
+
+	purged, err := p.recordService.PurgeTrash(ctx, deletedBefore)
+
+	if err != nil {
+		return err
+	}
+
+	if purged > 0 {
+		log.Printf("purged %d records", purged)
+	}
+
+	return nil
+}
+
+// Run purges right away and then at every interval until the context is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		err := p.Purge(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error while purging trash: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package deletion
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/apikey"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// interruptedAccessStore fails the first deletion of the accesses of a
+// namespace, as if the server stopped halfway through a purge.
+type interruptedAccessStore struct {
+	namespace.ApiKeyAccessStore
+	interrupted bool
+}
+
+var errInterrupted = errors.New("interrupted")
+
+func (s *interruptedAccessStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	if !s.interrupted {
+		s.interrupted = true
+
+		return errInterrupted
+	}
+
+	return s.ApiKeyAccessStore.DeleteByNamespaceID(ctx, namespaceID)
+}
+
+func TestInterruptedPurgeIsResumed(t *testing.T) {
+	ctx := context.Background()
+	db := embedded.NewMemoryDB()
+
+	accessStore := &interruptedAccessStore{ApiKeyAccessStore: namespace.NewApiKeyAccessEmbeddedStore(db)}
+	recordStore := dns.NewRecordEmbeddedStore(db)
+	revisionStore := dns.NewRecordRevisionEmbeddedStore(db)
+
+	namespaceService := namespace.NewService(namespace.NewEmbeddedStore(db))
+	apiKeyAccessService := namespace.NewApiKeyAccessService(accessStore, namespaceService, apikey.NewService(apikey.NewEmbeddedStore(db), 0))
+	recordService := dns.NewRecordService(recordStore, revisionStore, db, namespaceService)
+
+	job := NewNamespaceDeletionJob(namespaceService, apiKeyAccessService, recordService, time.Minute)
+	purger, err := NewPurger(namespaceService, recordService, job, 0, time.Minute)
+
+	if err != nil {
+		t.Fatalf("error creating purger: %v", err)
+	}
+
+	trashed, err := namespaceService.Create(ctx, &namespace.CreationRequest{Name: "trashed"}, "admin")
+
+	if err != nil {
+		t.Fatalf("error creating namespace: %v", err)
+	}
+
+	kept, err := namespaceService.Create(ctx, &namespace.CreationRequest{Name: "kept"}, "admin")
+
+	if err != nil {
+		t.Fatalf("error creating namespace: %v", err)
+	}
+
+	for _, created := range []*namespace.Namespace{trashed, kept} {
+		namespaceID := created.ID.Hex()
+		recordID := primitive.NewObjectID()
+
+		err = recordStore.Insert(ctx, &dns.Record{ID: recordID, NamespaceID: namespaceID, Name: created.Name + ".com", Type: dns.RecordTypeA, Value: "10.0.0.1", TTL: 60, Version: 1})
+
+		if err != nil {
+			t.Fatalf("error inserting record: %v", err)
+		}
+
+		err = revisionStore.Insert(ctx, &dns.RecordRevision{ID: primitive.NewObjectID(), NamespaceID: namespaceID, RecordID: recordID.Hex(), Version: 1})
+
+		if err != nil {
+			t.Fatalf("error inserting revision: %v", err)
+		}
+
+		err = accessStore.AddActions(ctx, namespaceID, primitive.NewObjectID().Hex(), []namespace.Action{namespace.ActionRead}, nil, "admin")
+
+		if err != nil {
+			t.Fatalf("error adding access: %v", err)
+		}
+	}
+
+	_, err = namespaceService.Trash(ctx, trashed.ID.Hex(), nil)
+
+	if err != nil {
+		t.Fatalf("error trashing namespace: %v", err)
+	}
+
+	err = purger.Purge(ctx)
+
+	if !errors.Is(err, errInterrupted) {
+		t.Fatalf("expected the purge to be interrupted, got %v", err)
+	}
+
+	deleting, err := namespaceService.ListDeleting(ctx)
+
+	if err != nil || len(deleting) != 1 || deleting[0].ID != trashed.ID {
+		t.Fatalf("expected the namespace to be left deleting, got %v, %v", deleting, err)
+	}
+
+	err = job.Resume(ctx)
+
+	if err != nil {
+		t.Fatalf("error resuming deletions: %v", err)
+	}
+
+	_, err = namespaceService.Get(ctx, trashed.ID.Hex())
+
+	if err == nil {
+		t.Fatalf("expected the namespace to be deleted")
+	}
+
+	deleting, err = namespaceService.ListDeleting(ctx)
+
+	if err != nil || len(deleting) != 0 {
+		t.Fatalf("expected no pending deletion, got %v, %v", deleting, err)
+	}
+
+	for _, checked := range []*namespace.Namespace{trashed, kept} {
+		namespaceID := checked.ID.Hex()
+		remaining := 0
+
+		if checked == kept {
+			remaining = 1
+		}
+
+		records, err := recordStore.List(ctx, namespaceID, 0, 10)
+
+		if err != nil || len(records) != remaining {
+			t.Fatalf("expected %d records in namespace %s, got %d, %v", remaining, checked.Name, len(records), err)
+		}
+
+		revisions, err := revisionStore.ListByNamespace(ctx, namespaceID, &pagination.Request{Size: 10})
+
+		if err != nil || revisions.Total != int64(remaining) {
+			t.Fatalf("expected %d revisions in namespace %s, got %+v, %v", remaining, checked.Name, revisions, err)
+		}
+
+		accesses, err := accessStore.List(ctx, namespaceID, &pagination.Request{Size: 10})
+
+		if err != nil || accesses.Total != int64(remaining) {
+			t.Fatalf("expected %d accesses in namespace %s, got %+v, %v", remaining, checked.Name, accesses, err)
+		}
+	}
+}
+
+func TestRestoredNamespaceIsNotMarkedDeleting(t *testing.T) {
+	ctx := context.Background()
+	namespaceService := namespace.NewService(namespace.NewEmbeddedStore(embedded.NewMemoryDB()))
+
+	created, err := namespaceService.Create(ctx, &namespace.CreationRequest{Name: "restored"}, "admin")
+
+	if err != nil {
+		t.Fatalf("error creating namespace: %v", err)
+	}
+
+	expired, err := namespaceService.Trash(ctx, created.ID.Hex(), nil)
+
+	if err != nil {
+		t.Fatalf("error trashing namespace: %v", err)
+	}
+
+	_, err = namespaceService.Restore(ctx, created.ID.Hex(), &expired.Version)
+
+	if err != nil {
+		t.Fatalf("error restoring namespace: %v", err)
+	}
+
+	_, err = namespaceService.MarkDeleting(ctx, expired)
+
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected marking the restored namespace deleting to conflict, got %v", err)
+	}
+
+	restored, err := namespaceService.Get(ctx, created.ID.Hex())
+
+	if err != nil || !restored.Active() {
+		t.Fatalf("expected the namespace to stay active, got %+v, %v", restored, err)
+	}
+}
+
+func TestPurgerRejectsNegativeRetention(t *testing.T) {
+	_, err := NewPurger(nil, nil, nil, -time.Hour, time.Minute)
+
+	if err == nil {
+		t.Fatalf("expected a negative retention to be rejected")
+	}
+}
+
+func TestPurgerRejectsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		_, err := NewPurger(nil, nil, nil, time.Hour, interval)
+
+		if err == nil {
+			t.Fatalf("expected an interval of %v to be rejected", interval)
+		}
+	}
+}
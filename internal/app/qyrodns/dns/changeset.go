@@ -0,0 +1,237 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+)
+
+// ApplyChanges validates all the changes against the current records of the
+// namespace, then applies them in one transaction with a single serial bump.
+func (s *RecordService) ApplyChanges(ctx context.Context, namespaceID string, request *RecordChangesRequest, actorType ActorType, actorID string) (*RecordChangesResponse, error) {
+	namespaceExists, err := s.namespaceService.Exists(ctx, namespaceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !namespaceExists {
+		return nil, fmt.Errorf("namespace not found")
+	}
+
+	records := make([]*Record, 0, len(request.Changes))
+
+	serial, err := s.change(ctx, namespaceID, func(ctx context.Context) error {
+		// The transaction may be retried.
+		records = records[:0]
+
+		err := s.validateChanges(ctx, namespaceID, request.Changes)
+
+		if err != nil {
+			return err
+		}
+
+		for _, change := range request.Changes {
+			var record *Record
+
+			switch change.Action {
+			case namespace.ActionCreate:
+				record, err = s.add(ctx, namespaceID, &RecordAdditionRequest{
+					Name:  change.Name,
+					Type:  change.Type,
+					Value: change.Value,
+					TTL:   change.TTL,
+					Class: change.Class,
+				}, actorType, actorID)
+			case namespace.ActionUpdate:
+				record, err = s.update(ctx, namespaceID, change.RecordID, change.updateRequest(), change.Version, actorType, actorID)
+			case namespace.ActionDelete:
+				record, err = s.delete(ctx, namespaceID, change.RecordID, change.Version, actorType, actorID)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			records = append(records, record)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordChangesResponse{Serial: serial, Records: records}, nil
+}
+
+// validateChanges applies the changes to a copy of the namespace records and
+// checks the result. Changes to records no longer at the expected version fail
+// with storage.ErrConflict.
+func (s *RecordService) validateChanges(ctx context.Context, namespaceID string, changes []RecordChange) error {
+	current, err := s.store.List(ctx, namespaceID, 0, 0)
+
+	if err != nil {
+		return err
+	}
+
+	state := make(map[string]*Record, len(current))
+
+	for _, record := range current {
+		state[record.ID.Hex()] = record
+	}
+
+	problems := make([]*ValidationProblem, 0)
+	touchedNames := make(map[string]bool)
+
+	for i, change := range changes {
+		switch change.Action {
+		case namespace.ActionCreate:
+			if change.Name == "" || change.Type == "" || change.Value == "" || change.Class == "" {
+				problems = append(problems, &ValidationProblem{
+					Change:  &i,
+					Code:    ProblemMissingField,
+					Message: "name, type, value, ttl and class are required",
+				})
+
+				continue
+			}
+
+			record := &Record{Name: change.Name, Type: change.Type, Value: change.Value, TTL: change.TTL, Class: change.Class}
+			state[fmt.Sprintf("change-%d", i)] = record
+			touchedNames[normalizeName(record.Name)] = true
+			problems = append(problems, withChange(i, validateRecord(record))...)
+		case namespace.ActionUpdate, namespace.ActionDelete:
+			record, ok := state[change.RecordID]
+
+			if !ok {
+				problems = append(problems, &ValidationProblem{
+					Change:   &i,
+					RecordID: change.RecordID,
+					Code:     ProblemNotFound,
+					Message:  fmt.Sprintf("record %s not found", change.RecordID),
+				})
+
+				continue
+			}
+
+			if change.Version != nil && *change.Version != record.Version {
+				return fmt.Errorf("change %d: record %s is at version %d: %w", i, change.RecordID, record.Version, storage.ErrConflict)
+			}
+
+			touchedNames[normalizeName(record.Name)] = true
+
+			if change.Action == namespace.ActionDelete {
+				delete(state, change.RecordID)
+				continue
+			}
+
+			updated := change.updateRequest().apply(record)
+			state[change.RecordID] = updated
+			touchedNames[normalizeName(updated.Name)] = true
+			problems = append(problems, withChange(i, validateRecord(updated))...)
+		}
+	}
+
+	problems = append(problems, namespaceProblems(state, touchedNames)...)
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// actions returns the distinct actions of the changeset, which an API key
+// needs all permissions for.
+func (r *RecordChangesRequest) actions() []namespace.Action {
+	actions := make([]namespace.Action, 0)
+
+	for _, change := range r.Changes {
+		if !slices.Contains(actions, change.Action) {
+			actions = append(actions, change.Action)
+		}
+	}
+
+	return actions
+}
+
+// apply returns a copy of the record with the fields of the request.
+func (r *RecordUpdateRequest) apply(record *Record) *Record {
+	updated := *record
+	updated.Version++
+
+	if r.Name != "" {
+		updated.Name = r.Name
+	}
+
+	if r.Type != "" {
+		updated.Type = r.Type
+	}
+
+	if r.Value != "" {
+		updated.Value = r.Value
+	}
+
+	if r.TTL != 0 {
+		updated.TTL = r.TTL
+	}
+
+	if r.Class != "" {
+		updated.Class = r.Class
+	}
+
+	return &updated
+}
+
+func (r *RecordAdditionRequest) change() RecordChange {
+	return RecordChange{
+		Action: namespace.ActionCreate,
+		Name:   r.Name,
+		Type:   r.Type,
+		Value:  r.Value,
+		TTL:    r.TTL,
+		Class:  r.Class,
+	}
+}
+
+func (r *RecordUpdateRequest) change(recordID string, version *int64) RecordChange {
+	return RecordChange{
+		Action:   namespace.ActionUpdate,
+		RecordID: recordID,
+		Version:  version,
+		Name:     r.Name,
+		Type:     r.Type,
+		Value:    r.Value,
+		TTL:      r.TTL,
+		Class:    r.Class,
+	}
+}
+
+func (c *RecordChange) updateRequest() *RecordUpdateRequest {
+	return &RecordUpdateRequest{
+		Name:  c.Name,
+		Type:  c.Type,
+		Value: c.Value,
+		TTL:   c.TTL,
+		Class: c.Class,
+	}
+}
+
+// withChange sets the index of the change the problems were found in.
+func withChange(i int, problems []*ValidationProblem) []*ValidationProblem {
+	for _, problem := range problems {
+		problem.Change = &i
+	}
+
+	return problems
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,268 @@
+package dns
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RecordEmbeddedStore struct {
+	records *embedded.Collection[Record]
+}
+
+func NewRecordEmbeddedStore(db embedded.DB) *RecordEmbeddedStore {
+	return &RecordEmbeddedStore{records: embedded.NewCollection[Record](db, "records")}
+}
+
+func (s *RecordEmbeddedStore) Insert(ctx context.Context, record *Record) error {
+	return s.records.Put(ctx, record.ID.Hex(), record)
+}
+
+func (s *RecordEmbeddedStore) List(ctx context.Context, namespaceID string, page int64, size int64) ([]*Record, error) {
+	records, err := s.records.Find(ctx, func(record *Record) bool {
+		return record.NamespaceID == namespaceID && record.DeletedAt == nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return embedded.Page(records, page, size), nil
+}
+
+func (s *RecordEmbeddedStore) Search(ctx context.Context, namespaceID string, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error) {
+	return s.search(ctx, filter, request, func(record *Record) bool {
+		return record.NamespaceID == namespaceID
+	})
+}
+
+func (s *RecordEmbeddedStore) SearchAll(ctx context.Context, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error) {
+	return s.search(ctx, filter, request, func(record *Record) bool {
+		return true
+	})
+}
+
+func (s *RecordEmbeddedStore) search(ctx context.Context, filter *RecordFilter, request *pagination.Request, scope func(record *Record) bool) (*pagination.Page[Record], error) {
+	name, err := regexp.Compile("(?i)" + filter.namePattern())
+
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := regexp.Compile("(?i)" + filter.valuePattern())
+
+	if err != nil {
+		return nil, err
+	}
+
+	scopeName, err := regexp.Compile("(?i)" + filter.scopePattern())
+
+	if err != nil {
+		return nil, err
+	}
+
+	scopeTypes := filter.scopeTypes()
+
+	records, err := s.records.Find(ctx, func(record *Record) bool {
+		return scope(record) && record.DeletedAt == nil &&
+			!slices.Contains(filter.excludedNamespaceIDs, record.NamespaceID) &&
+			name.MatchString(record.Name) &&
+			value.MatchString(record.Value) &&
+			scopeName.MatchString(record.Name) &&
+			(len(scopeTypes) == 0 || slices.Contains(scopeTypes, string(record.Type))) &&
+			(filter.Type == "" || record.Type == filter.Type) &&
+			(filter.CreatorType == "" || record.CreatorType == filter.CreatorType) &&
+			(filter.CreatorID == "" || record.CreatorID == filter.CreatorID) &&
+			!record.UpdatedAt.Before(filter.UpdatedSince)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(records, request, filter.order(), filter.cursor), nil
+}
+
+func (s *RecordEmbeddedStore) ListAll(ctx context.Context) ([]*Record, error) {
+	return s.records.Find(ctx, func(record *Record) bool {
+		return record.DeletedAt == nil
+	})
+}
+
+func (s *RecordEmbeddedStore) Get(ctx context.Context, namespaceID string, id primitive.ObjectID) (*Record, error) {
+	return s.get(ctx, namespaceID, id, false)
+}
+
+func (s *RecordEmbeddedStore) Update(ctx context.Context, namespaceID string, id primitive.ObjectID, request *RecordUpdateRequest, version int64) (*Record, error) {
+	var record *Record
+
+	err := s.records.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		record, err = s.Get(ctx, namespaceID, id)
+
+		if err != nil {
+			return err
+		}
+
+		if record.Version != version {
+			return storage.ErrConflict
+		}
+
+		record.Version++
+		record.UpdatedAt = time.Now()
+
+		if request.Name != "" {
+			record.Name = request.Name
+		}
+
+		if request.Value != "" {
+			record.Value = request.Value
+		}
+
+		if request.Type != "" {
+			record.Type = request.Type
+		}
+
+		if request.Class != "" {
+			record.Class = request.Class
+		}
+
+		if request.TTL != 0 {
+			record.TTL = request.TTL
+		}
+
+		return s.records.Put(ctx, id.Hex(), record)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (s *RecordEmbeddedStore) Query(ctx context.Context, names []string, recordType RecordType) ([]*Record, error) {
+	return s.records.Find(ctx, func(record *Record) bool {
+		return record.Type == recordType && slices.Contains(names, record.Name) && record.DeletedAt == nil
+	})
+}
+
+func (s *RecordEmbeddedStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	_, err := s.records.DeleteWhere(ctx, func(record *Record) bool {
+		return record.NamespaceID == namespaceID
+	})
+
+	return err
+}
+
+func (s *RecordEmbeddedStore) Trash(ctx context.Context, namespaceID string, id primitive.ObjectID, version int64) (*Record, error) {
+	return s.modify(ctx, namespaceID, id, false, func(record *Record) error {
+		if record.Version != version {
+			return storage.ErrConflict
+		}
+
+		now := time.Now()
+		record.DeletedAt = &now
+		record.Version++
+
+		return nil
+	})
+}
+
+func (s *RecordEmbeddedStore) ListTrash(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[Record], error) {
+	records, err := s.records.Find(ctx, func(record *Record) bool {
+		return record.NamespaceID == namespaceID && record.DeletedAt != nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(records, request, pagination.Order{}, recordCursor), nil
+}
+
+func (s *RecordEmbeddedStore) Restore(ctx context.Context, namespaceID string, id primitive.ObjectID) (*Record, error) {
+	return s.modify(ctx, namespaceID, id, true, func(record *Record) error {
+		record.DeletedAt = nil
+		record.UpdatedAt = time.Now()
+		record.Version++
+
+		return nil
+	})
+}
+
+func (s *RecordEmbeddedStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return s.records.DeleteWhere(ctx, func(record *Record) bool {
+		return record.DeletedAt != nil && record.DeletedAt.Before(deletedBefore)
+	})
+}
+
+func (s *RecordEmbeddedStore) Replace(ctx context.Context, record *Record, version int64) error {
+	return s.records.WithTransaction(ctx, func(ctx context.Context) error {
+		existing, err := s.records.Get(ctx, record.ID.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		if existing.NamespaceID != record.NamespaceID {
+			return storage.ErrNotFound
+		}
+
+		if existing.Version != version {
+			return storage.ErrConflict
+		}
+
+		return s.records.Put(ctx, record.ID.Hex(), record)
+	})
+}
+
+// get returns the record of the namespace, either live or in the trash.
+func (s *RecordEmbeddedStore) get(ctx context.Context, namespaceID string, id primitive.ObjectID, trashed bool) (*Record, error) {
+	record, err := s.records.Get(ctx, id.Hex())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if record.NamespaceID != namespaceID || (record.DeletedAt != nil) != trashed {
+		return nil, storage.ErrNotFound
+	}
+
+	return record, nil
+}
+
+func (s *RecordEmbeddedStore) modify(ctx context.Context, namespaceID string, id primitive.ObjectID, trashed bool, change func(record *Record) error) (*Record, error) {
+	var record *Record
+
+	err := s.records.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		record, err = s.get(ctx, namespaceID, id, trashed)
+
+		if err != nil {
+			return err
+		}
+
+		err = change(record)
+
+		if err != nil {
+			return err
+		}
+
+		return s.records.Put(ctx, id.Hex(), record)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
@@ -0,0 +1,93 @@
+package dns
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+)
+
+// namePattern returns the case-insensitive regular expression matching the
+// names of the filter, or an empty string when it has no name.
+func (f *RecordFilter) namePattern() string {
+	return matchPattern(f.Name, f.NameMatch)
+}
+
+// valuePattern returns the case-insensitive regular expression matching the
+// values of the filter, or an empty string when it has no value.
+func (f *RecordFilter) valuePattern() string {
+	return matchPattern(f.Value, f.ValueMatch)
+}
+
+// scopePattern returns the case-insensitive regular expression matching the
+// names in scope, or an empty string when every name is.
+func (f *RecordFilter) scopePattern() string {
+	if f.scope == nil || len(f.scope.NamePatterns) == 0 {
+		return ""
+	}
+
+	patterns := make([]string, len(f.scope.NamePatterns))
+
+	for i, pattern := range f.scope.NamePatterns {
+		patterns[i] = "(?:" + namespace.NamePattern(pattern) + ")"
+	}
+
+	return strings.Join(patterns, "|")
+}
+
+// scopeTypes returns the types in scope, or nil when every type is.
+func (f *RecordFilter) scopeTypes() []string {
+	if f.scope == nil {
+		return nil
+	}
+
+	return f.scope.Types
+}
+
+// matchPattern matches the text as the match says, regardless of a trailing
+// dot.
+func matchPattern(text string, match NameMatch) string {
+	if text == "" {
+		return ""
+	}
+
+	text = regexp.QuoteMeta(strings.TrimSuffix(text, "."))
+
+	switch match {
+	case NameMatchPrefix:
+		return "^" + text
+	case NameMatchSuffix:
+		return text + `\.?$`
+	case NameMatchContains:
+		return text
+	default:
+		return "^" + text + `\.?$`
+	}
+}
+
+// order returns the order of the listing. Records get increasing IDs as they
+// are created, so the creation order is the order of their IDs.
+func (f *RecordFilter) order() pagination.Order {
+	field, descending := strings.CutPrefix(f.Sort, "-")
+
+	if field == "created_at" {
+		field = ""
+	}
+
+	return pagination.Order{Field: field, Descending: descending}
+}
+
+// cursor returns the position of the record in the order of the listing.
+func (f *RecordFilter) cursor(record *Record) pagination.Cursor {
+	cursor := pagination.Cursor{ID: record.ID}
+
+	switch f.order().Field {
+	case "name":
+		cursor.Value = record.Name
+	case "type":
+		cursor.Value = string(record.Type)
+	}
+
+	return cursor
+}
@@ -13,11 +13,13 @@ import (
 
 type Handler struct {
 	recordService *RecordService
+	staleCache    *StaleCache
 }
 
-func NewHandler(recordService *RecordService) *Handler {
+func NewHandler(recordService *RecordService, staleCache *StaleCache) *Handler {
 	return &Handler{
 		recordService: recordService,
+		staleCache:    staleCache,
 	}
 }
 
@@ -40,7 +42,7 @@ func (h *Handler) Handle(w dns.ResponseWriter, r *dns.Msg) {
 			continue
 		}
 
-		records, err := h.recordService.Query(ctx, q.Name, recordType)
+		records, err := h.query(ctx, q.Name, recordType)
 
 		if err != nil {
 			log.Printf("error querying records: %v", err)
@@ -69,6 +71,27 @@ func (h *Handler) Handle(w dns.ResponseWriter, r *dns.Msg) {
 	}
 }
 
+func (h *Handler) query(ctx context.Context, name string, recordType RecordType) ([]*Record, error) {
+	records, err := h.recordService.Query(ctx, name, recordType)
+
+	if err == nil {
+		h.staleCache.MarkHealthy()
+		return records, nil
+	}
+
+	h.staleCache.MarkDegraded()
+
+	staleRecords, ok := h.staleCache.Query(name, recordType)
+
+	if !ok {
+		return nil, fmt.Errorf("%w (no snapshot within maximum staleness)", err)
+	}
+
+	log.Printf("serving stale answer for %s %s: %v", name, recordType, err)
+
+	return staleRecords, nil
+}
+
 func (h *Handler) createResourceRecord(record *Record, qtype uint16) dns.RR {
 	recordName := strings.TrimSuffix(record.Name, ".")
 	recordName = fmt.Sprintf("%s.", recordName)
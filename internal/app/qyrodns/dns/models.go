@@ -2,6 +2,7 @@ package dns
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/miekg/dns"
@@ -16,12 +17,41 @@ type Record struct {
 	Value       string             `bson:"value" json:"value"`
 	TTL         uint32             `bson:"ttl" json:"ttl"`
 	Class       RecordClass        `bson:"class" json:"class"`
-	CreatorType ActorType          `bson:"creator_type" json:"creator_type"`
-	CreatorID   string             `bson:"creator_id" json:"creator_id"`
+	// Version is incremented on every change and served as the ETag.
+	Version     int64     `bson:"version" json:"version"`
+	CreatorType ActorType `bson:"creator_type" json:"creator_type"`
+	CreatorID   string    `bson:"creator_id" json:"creator_id"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+	// DeletedAt is set while the record is in the trash.
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+// RecordRevision is one change of a record. Before is nil for a creation and
+// After for a permanent deletion.
+type RecordRevision struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	NamespaceID string             `bson:"namespace_id" json:"namespace_id"`
+	RecordID    string             `bson:"record_id" json:"record_id"`
+	Version     int64              `bson:"version" json:"version"`
+	Action      RevisionAction     `bson:"action" json:"action"`
+	ActorType   ActorType          `bson:"actor_type" json:"actor_type"`
+	ActorID     string             `bson:"actor_id" json:"actor_id"`
+	Before      *Record            `bson:"before" json:"before"`
+	After       *Record            `bson:"after" json:"after"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+type RevisionAction string
+
+const (
+	RevisionActionCreate   RevisionAction = "create"
+	RevisionActionUpdate   RevisionAction = "update"
+	RevisionActionDelete   RevisionAction = "delete"
+	RevisionActionRestore  RevisionAction = "restore"
+	RevisionActionRollback RevisionAction = "rollback"
+)
+
 type ActorType string
 
 const (
@@ -56,6 +86,18 @@ const (
 	RecordTypeNS    RecordType = "NS"
 )
 
+// ParseRecordType returns the supported record type of the given name, such
+// as "A" or "mx".
+func ParseRecordType(name string) (RecordType, error) {
+	dnsType, ok := dns.StringToType[strings.ToUpper(name)]
+
+	if !ok {
+		return "", fmt.Errorf("record type %s is not supported", name)
+	}
+
+	return GetRecordType(dns.Type(dnsType))
+}
+
 func GetRecordType(dnsType dns.Type) (RecordType, error) {
 	switch dnsType {
 	case dns.Type(dns.TypeA):
@@ -0,0 +1,300 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecordMongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewRecordMongoStore(mongo *mongo.Collection) *RecordMongoStore {
+	return &RecordMongoStore{mongo: mongo}
+}
+
+func (s *RecordMongoStore) Insert(ctx context.Context, record *Record) error {
+	_, err := s.mongo.InsertOne(ctx, record)
+
+	return err
+}
+
+func (s *RecordMongoStore) List(ctx context.Context, namespaceID string, page int64, size int64) ([]*Record, error) {
+	return s.find(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"deleted_at":   nil,
+	}, options.Find().SetSkip(page*size).SetLimit(size))
+}
+
+func (s *RecordMongoStore) Search(ctx context.Context, namespaceID string, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error) {
+	query := searchQuery(filter)
+	query["namespace_id"] = namespaceID
+
+	return pagination.Find(ctx, s.mongo, query, request, filter.order(), filter.cursor)
+}
+
+func (s *RecordMongoStore) SearchAll(ctx context.Context, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error) {
+	return pagination.Find(ctx, s.mongo, searchQuery(filter), request, filter.order(), filter.cursor)
+}
+
+func searchQuery(filter *RecordFilter) bson.M {
+	query := bson.M{
+		"deleted_at": nil,
+	}
+
+	if pattern := filter.namePattern(); pattern != "" {
+		query["name"] = primitive.Regex{Pattern: pattern, Options: "i"}
+	}
+
+	if pattern := filter.valuePattern(); pattern != "" {
+		query["value"] = primitive.Regex{Pattern: pattern, Options: "i"}
+	}
+
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+
+	if filter.CreatorType != "" {
+		query["creator_type"] = filter.CreatorType
+	}
+
+	if filter.CreatorID != "" {
+		query["creator_id"] = filter.CreatorID
+	}
+
+	if !filter.UpdatedSince.IsZero() {
+		query["updated_at"] = bson.M{"$gte": filter.UpdatedSince}
+	}
+
+	if len(filter.excludedNamespaceIDs) > 0 {
+		query["namespace_id"] = bson.M{"$nin": filter.excludedNamespaceIDs}
+	}
+
+	scope := bson.A{}
+
+	if pattern := filter.scopePattern(); pattern != "" {
+		scope = append(scope, bson.M{"name": primitive.Regex{Pattern: pattern, Options: "i"}})
+	}
+
+	if types := filter.scopeTypes(); len(types) > 0 {
+		scope = append(scope, bson.M{"type": bson.M{"$in": types}})
+	}
+
+	if len(scope) > 0 {
+		query["$and"] = scope
+	}
+
+	return query
+}
+
+func (s *RecordMongoStore) ListAll(ctx context.Context) ([]*Record, error) {
+	return s.find(ctx, bson.M{"deleted_at": nil})
+}
+
+func (s *RecordMongoStore) Get(ctx context.Context, namespaceID string, id primitive.ObjectID) (*Record, error) {
+	result := s.mongo.FindOne(ctx, bson.M{
+		"_id":          id,
+		"namespace_id": namespaceID,
+		"deleted_at":   nil,
+	})
+
+	return s.decode(result)
+}
+
+func (s *RecordMongoStore) Update(ctx context.Context, namespaceID string, id primitive.ObjectID, request *RecordUpdateRequest, version int64) (*Record, error) {
+	fields := bson.M{
+		"updated_at": time.Now(),
+	}
+
+	if request.Name != "" {
+		fields["name"] = request.Name
+	}
+
+	if request.Value != "" {
+		fields["value"] = request.Value
+	}
+
+	if request.Type != "" {
+		fields["type"] = request.Type
+	}
+
+	if request.Class != "" {
+		fields["class"] = request.Class
+	}
+
+	if request.TTL != 0 {
+		fields["ttl"] = request.TTL
+	}
+
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
+		"_id":          id,
+		"namespace_id": namespaceID,
+		"deleted_at":   nil,
+		"version":      storage.VersionFilter(version),
+	}, bson.M{
+		"$set": fields,
+		"$inc": bson.M{"version": 1},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decodeVersioned(ctx, namespaceID, id, result)
+}
+
+func (s *RecordMongoStore) Query(ctx context.Context, names []string, recordType RecordType) ([]*Record, error) {
+	return s.find(ctx, bson.M{
+		"name": bson.M{
+			"$in": names,
+		},
+		"type":       recordType,
+		"deleted_at": nil,
+	})
+}
+
+func (s *RecordMongoStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	_, err := s.mongo.DeleteMany(ctx, bson.M{
+		"namespace_id": namespaceID,
+	})
+
+	return err
+}
+
+func (s *RecordMongoStore) Trash(ctx context.Context, namespaceID string, id primitive.ObjectID, version int64) (*Record, error) {
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
+		"_id":          id,
+		"namespace_id": namespaceID,
+		"deleted_at":   nil,
+		"version":      storage.VersionFilter(version),
+	}, bson.M{
+		"$set": bson.M{"deleted_at": time.Now()},
+		"$inc": bson.M{"version": 1},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decodeVersioned(ctx, namespaceID, id, result)
+}
+
+func (s *RecordMongoStore) ListTrash(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[Record], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"namespace_id": namespaceID,
+		"deleted_at":   bson.M{"$ne": nil},
+	}, request, pagination.Order{}, recordCursor)
+}
+
+func (s *RecordMongoStore) Restore(ctx context.Context, namespaceID string, id primitive.ObjectID) (*Record, error) {
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
+		"_id":          id,
+		"namespace_id": namespaceID,
+		"deleted_at":   bson.M{"$ne": nil},
+	}, bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"deleted_at": ""},
+		"$inc":   bson.M{"version": 1},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decode(result)
+}
+
+func (s *RecordMongoStore) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := s.mongo.DeleteMany(ctx, bson.M{
+		"deleted_at": bson.M{"$lt": deletedBefore},
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (s *RecordMongoStore) Replace(ctx context.Context, record *Record, version int64) error {
+	result, err := s.mongo.ReplaceOne(ctx, bson.M{
+		"_id":          record.ID,
+		"namespace_id": record.NamespaceID,
+		"version":      storage.VersionFilter(version),
+	}, record)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	err = s.mongo.FindOne(ctx, bson.M{
+		"_id":          record.ID,
+		"namespace_id": record.NamespaceID,
+	}).Err()
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return storage.ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return storage.ErrConflict
+}
+
+func (s *RecordMongoStore) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*Record, error) {
+	result, err := s.mongo.Find(ctx, filter, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0)
+
+	err = result.All(ctx, &records)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// decodeVersioned decodes the result of a change made at a given version,
+// telling a missing record from one at another version.
+func (s *RecordMongoStore) decodeVersioned(ctx context.Context, namespaceID string, id primitive.ObjectID, result *mongo.SingleResult) (*Record, error) {
+	record, err := s.decode(result)
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return record, err
+	}
+
+	_, err = s.Get(ctx, namespaceID, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, storage.ErrConflict
+}
+
+func (s *RecordMongoStore) decode(result *mongo.SingleResult) (*Record, error) {
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, storage.ErrNotFound
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	record := &Record{}
+
+	err := result.Decode(record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
@@ -2,22 +2,24 @@ package dns
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/etag"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
 type RecordAdminHandler struct {
 	router        *gin.Engine
-	authenticator *auth.Authenticator
+	authorizer    *admin.Authorizer
 	recordService *RecordService
 }
 
-func NewRecordAdminHandler(router *gin.Engine, authenticator *auth.Authenticator, recordService *RecordService) *RecordAdminHandler {
-	return &RecordAdminHandler{router: router, authenticator: authenticator, recordService: recordService}
+func NewRecordAdminHandler(router *gin.Engine, authorizer *admin.Authorizer, recordService *RecordService) *RecordAdminHandler {
+	return &RecordAdminHandler{router: router, authorizer: authorizer, recordService: recordService}
 }
 
 func (h *RecordAdminHandler) Register() {
@@ -26,17 +28,142 @@ func (h *RecordAdminHandler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		var req RecordAdditionRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		record, err := h.recordService.Add(ctx, namespaceID, &req, ActorTypeAdmin, aa.ID.Hex())
 
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			respondValidationError(c, err)
+
+			return
+		}
+
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusCreated, record)
+	})
+
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		request, err := pagination.FromQuery(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
+
 			return
 		}
 
-		var req RecordAdditionRequest
+		var filter RecordFilter
+
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		records, err := h.recordService.List(ctx, namespaceID, &filter, request)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, records)
+	})
+
+	h.router.GET("/admin/api/v1/records", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, "")
+
+		if !ok {
+			return
+		}
+
+		request, err := pagination.FromQuery(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		var filter RecordFilter
+
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		results, err := h.recordService.Search(ctx, &filter, request)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, results)
+	})
+
+	h.router.POST("/admin/api/v1/records/replace", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		aa, ok := h.authorizer.Authenticate(c, ctx)
+
+		if !ok {
+			return
+		}
+
+		var req RecordReplacementRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -47,9 +174,46 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
+		// Replacing across every namespace needs write access to all of them.
+		namespaceIDs := req.NamespaceIDs
+
+		if len(namespaceIDs) == 0 {
+			namespaceIDs = []string{""}
+		}
+
+		for _, namespaceID := range namespaceIDs {
+			if !aa.Allows(admin.PermissionWrite, namespaceID) {
+				admin.Forbid(c)
+
+				return
+			}
+		}
+
+		response, err := h.recordService.ReplaceValues(ctx, &req, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	})
+
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
 		namespaceID := c.Param("namespaceID")
+		recordID := c.Param("recordID")
 
-		record, err := h.recordService.Add(ctx, namespaceID, &req, ActorTypeAdmin, aa.ID)
+		record, err := h.recordService.Get(ctx, namespaceID, recordID)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -60,16 +224,38 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusCreated, record)
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
 	})
 
-	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records", func(c *gin.Context) {
+	h.router.PUT("/admin/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+		recordID := c.Param("recordID")
+
+		var req RecordUpdateRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		ifMatch, err := etag.IfMatch(c)
+
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -77,11 +263,32 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
+		record, err := h.recordService.Update(ctx, namespaceID, recordID, &req, ifMatch, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
+	})
+
+	h.router.DELETE("/admin/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
 		namespaceID := c.Param("namespaceID")
+		recordID := c.Param("recordID")
 
-		page := c.DefaultQuery("page", "0")
-		size := c.DefaultQuery("size", "50")
-		pageInt, err := strconv.ParseInt(page, 10, 64)
+		ifMatch, err := etag.IfMatch(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -92,7 +299,34 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		sizeInt, err := strconv.ParseInt(size, 10, 64)
+		record, err := h.recordService.Delete(ctx, namespaceID, recordID, ifMatch, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			c.JSON(etag.Status(err), gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
+	})
+
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records/trash", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		request, err := pagination.FromQuery(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -103,7 +337,7 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		records, err := h.recordService.List(ctx, namespaceID, pageInt, sizeInt)
+		records, err := h.recordService.ListTrash(ctx, namespaceID, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -117,14 +351,23 @@ func (h *RecordAdminHandler) Register() {
 		c.JSON(http.StatusOK, records)
 	})
 
-	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+	h.router.POST("/admin/api/v1/namespaces/:namespaceID/records/:recordID/restore", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+		recordID := c.Param("recordID")
+
+		record, err := h.recordService.Restore(ctx, namespaceID, recordID, ActorTypeAdmin, aa.ID.Hex())
 
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -132,10 +375,72 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
+	})
+
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records/history", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		request, err := pagination.FromQuery(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		revisions, err := h.recordService.NamespaceHistory(ctx, namespaceID, request)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	})
+
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records/:recordID/history", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
 		namespaceID := c.Param("namespaceID")
 		recordID := c.Param("recordID")
 
-		record, err := h.recordService.Get(ctx, namespaceID, recordID)
+		request, err := pagination.FromQuery(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		revisions, err := h.recordService.History(ctx, namespaceID, recordID, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -146,16 +451,60 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, record)
+		c.JSON(http.StatusOK, revisions)
 	})
 
-	h.router.PUT("/admin/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+	h.router.POST("/admin/api/v1/namespaces/:namespaceID/rollback", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		var req RollbackRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		revisions, err := h.recordService.Rollback(ctx, namespaceID, req.Timestamp, ActorTypeAdmin, aa.ID.Hex())
+
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			c.JSON(etag.Status(err), gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, revisions)
+	})
+
+	h.router.POST("/admin/api/v1/namespaces/:namespaceID/records/changes", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		var req RecordChangesRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -164,9 +513,29 @@ func (h *RecordAdminHandler) Register() {
 		}
 
 		namespaceID := c.Param("namespaceID")
-		recordID := c.Param("recordID")
 
-		var req RecordUpdateRequest
+		response, err := h.recordService.ApplyChanges(ctx, namespaceID, &req, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	})
+
+	h.router.POST("/admin/api/v1/namespaces/:namespaceID/records/sync", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		var req RecordSyncRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -177,7 +546,9 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		record, err := h.recordService.Update(ctx, namespaceID, recordID, &req)
+		namespaceID := c.Param("namespaceID")
+
+		plan, err := h.recordService.PlanSync(ctx, namespaceID, &req, ActorTypeAdmin, aa.ID.Hex())
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -188,17 +559,147 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, record)
+		plan, err = h.recordService.ApplySync(ctx, namespaceID, plan, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, plan)
 	})
 
-	h.router.DELETE("/admin/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/records/lint", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		lint, err := h.recordService.Lint(ctx, c.Param("namespaceID"))
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, lint)
+	})
+
+	h.router.GET("/admin/api/v1/namespaces/:namespaceID/rrsets/:name/:type", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		recordType, err := ParseRecordType(c.Param("type"))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		rrset, err := h.recordService.GetRRset(ctx, c.Param("namespaceID"), c.Param("name"), recordType)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, rrset)
+	})
+
+	h.router.PUT("/admin/api/v1/namespaces/:namespaceID/rrsets/:name/:type", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		recordType, err := ParseRecordType(c.Param("type"))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		var req RRsetReplacementRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+		name := c.Param("name")
+
+		changes, err := h.recordService.RRsetReplacementChanges(ctx, namespaceID, name, recordType, &req)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		rrset, err := h.recordService.ReplaceRRset(ctx, namespaceID, name, recordType, changes, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, rrset)
+	})
+
+	h.router.DELETE("/admin/api/v1/namespaces/:namespaceID/rrsets/:name/:type", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
+
+		if !ok {
+			return
+		}
+
+		recordType, err := ParseRecordType(c.Param("type"))
 
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -207,9 +708,9 @@ func (h *RecordAdminHandler) Register() {
 		}
 
 		namespaceID := c.Param("namespaceID")
-		recordID := c.Param("recordID")
+		name := c.Param("name")
 
-		record, err := h.recordService.Delete(ctx, namespaceID, recordID)
+		changes, err := h.recordService.RRsetDeletionChanges(ctx, namespaceID, name, recordType)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -220,6 +721,35 @@ func (h *RecordAdminHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, record)
+		rrset, err := h.recordService.DeleteRRset(ctx, namespaceID, name, recordType, changes, ActorTypeAdmin, aa.ID.Hex())
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, rrset)
+	})
+}
+
+// respondValidationError answers with the problems of invalid records, or
+// with the status of other errors.
+func respondValidationError(c *gin.Context, err error) {
+	var validationError *ValidationError
+
+	if errors.As(err, &validationError) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+			"errors":  validationError.Problems,
+		})
+
+		return
+	}
+
+	c.JSON(etag.Status(err), gin.H{
+		"success": false,
+		"message": err.Error(),
 	})
 }
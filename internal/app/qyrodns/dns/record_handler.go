@@ -2,13 +2,15 @@ package dns
 
 import (
 	"context"
+	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
 	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/pkg/etag"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
 type RecordHandler struct {
@@ -41,10 +43,46 @@ func (h *RecordHandler) Register() {
 
 		namespaceID := c.Param("namespaceID")
 
-		hasPermission, err := h.apiKeyAccessService.HasPermission(ctx, namespaceID, apiKey.ID, namespace.ActionCreate)
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionCreate)
+
+		if !ok {
+			return
+		}
+
+		var req RecordAdditionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		if !authorizeRecords(c, scope, &Record{Name: req.Name, Type: req.Type}) {
+			return
+		}
+
+		record, err := h.recordService.Add(ctx, namespaceID, &req, ActorTypeApiKey, apiKey.ID)
 
 		if err != nil {
-			c.JSON(http.StatusForbidden, gin.H{
+			respondValidationError(c, err)
+
+			return
+		}
+
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusCreated, record)
+	})
+
+	h.router.GET("/api/v1/namespaces/:namespaceID/records", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -52,17 +90,28 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{
+		namespaceID := c.Param("namespaceID")
+
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionRead)
+
+		if !ok {
+			return
+		}
+
+		request, err := pagination.FromQuery(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Permission denied",
+				"message": err.Error(),
 			})
 
 			return
 		}
 
-		var req RecordAdditionRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
+		var filter RecordFilter
+
+		if err := c.ShouldBindQuery(&filter); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -71,7 +120,9 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		record, err := h.recordService.Add(ctx, namespaceID, &req, ActorTypeApiKey, apiKey.ID)
+		filter.scope = scope
+
+		records, err := h.recordService.List(ctx, namespaceID, &filter, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -82,10 +133,10 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusCreated, record)
+		c.JSON(http.StatusOK, records)
 	})
 
-	h.router.GET("/api/v1/namespaces/:namespaceID/records", func(c *gin.Context) {
+	h.router.GET("/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
@@ -102,10 +153,17 @@ func (h *RecordHandler) Register() {
 
 		namespaceID := c.Param("namespaceID")
 
-		hasPermission, err := h.apiKeyAccessService.HasPermission(ctx, namespaceID, apiKey.ID, namespace.ActionRead)
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionRead)
+
+		if !ok {
+			return
+		}
+
+		recordID := c.Param("recordID")
+		record, err := h.recordService.Get(ctx, namespaceID, recordID)
 
 		if err != nil {
-			c.JSON(http.StatusForbidden, gin.H{
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -113,18 +171,51 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{
+		if !authorizeRecords(c, scope, record) {
+			return
+		}
+
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
+	})
+
+	h.router.PUT("/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
-				"message": "Permission denied",
+				"message": err.Error(),
 			})
 
 			return
 		}
 
-		page := c.DefaultQuery("page", "0")
-		size := c.DefaultQuery("size", "50")
-		pageInt, err := strconv.ParseInt(page, 10, 64)
+		namespaceID := c.Param("namespaceID")
+
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionUpdate)
+
+		if !ok {
+			return
+		}
+
+		recordID := c.Param("recordID")
+
+		var req RecordUpdateRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		ifMatch, err := etag.IfMatch(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -135,7 +226,48 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		sizeInt, err := strconv.ParseInt(size, 10, 64)
+		if !h.authorizeRecord(c, ctx, scope, namespaceID, recordID, &req) {
+			return
+		}
+
+		record, err := h.recordService.Update(ctx, namespaceID, recordID, &req, ifMatch, ActorTypeApiKey, apiKey.ID)
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
+	})
+
+	h.router.DELETE("/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionDelete)
+
+		if !ok {
+			return
+		}
+
+		recordID := c.Param("recordID")
+
+		ifMatch, err := etag.IfMatch(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -146,10 +278,14 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		records, err := h.recordService.List(ctx, namespaceID, pageInt, sizeInt)
+		if !h.authorizeRecord(c, ctx, scope, namespaceID, recordID, nil) {
+			return
+		}
+
+		record, err := h.recordService.Delete(ctx, namespaceID, recordID, ifMatch, ActorTypeApiKey, apiKey.ID)
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(etag.Status(err), gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -157,10 +293,11 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, records)
+		etag.Set(c, record.Version)
+		c.JSON(http.StatusOK, record)
 	})
 
-	h.router.GET("/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+	h.router.POST("/api/v1/namespaces/:namespaceID/records/changes", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
@@ -175,12 +312,44 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
+		var req RecordChangesRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
 		namespaceID := c.Param("namespaceID")
 
-		hasPermission, err := h.apiKeyAccessService.HasPermission(ctx, namespaceID, apiKey.ID, namespace.ActionRead)
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, req.actions()...)
+
+		if !ok || !h.authorizeChanges(c, ctx, scope, namespaceID, req.Changes) {
+			return
+		}
+
+		response, err := h.recordService.ApplyChanges(ctx, namespaceID, &req, ActorTypeApiKey, apiKey.ID)
 
 		if err != nil {
-			c.JSON(http.StatusForbidden, gin.H{
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, response)
+	})
+
+	h.router.POST("/api/v1/namespaces/:namespaceID/records/sync", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -188,17 +357,28 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{
+		var req RecordSyncRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Permission denied",
+				"message": err.Error(),
 			})
 
 			return
 		}
 
-		recordID := c.Param("recordID")
-		record, err := h.recordService.Get(ctx, namespaceID, recordID)
+		namespaceID := c.Param("namespaceID")
+
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionRead)
+
+		if !ok {
+			return
+		}
+
+		req.scope = scope
+
+		plan, err := h.recordService.PlanSync(ctx, namespaceID, &req, ActorTypeApiKey, apiKey.ID)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -209,10 +389,29 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, record)
+		if !h.authorizeChanges(c, ctx, scope, namespaceID, plan.Changes) {
+			return
+		}
+
+		// Applying the plan takes the permissions of the changes it makes.
+		if !plan.DryRun {
+			if _, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, plan.actions()...); !ok {
+				return
+			}
+		}
+
+		plan, err = h.recordService.ApplySync(ctx, namespaceID, plan, ActorTypeApiKey, apiKey.ID)
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, plan)
 	})
 
-	h.router.PUT("/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+	h.router.GET("/api/v1/namespaces/:namespaceID/records/lint", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
@@ -229,10 +428,26 @@ func (h *RecordHandler) Register() {
 
 		namespaceID := c.Param("namespaceID")
 
-		hasPermission, err := h.apiKeyAccessService.HasPermission(ctx, namespaceID, apiKey.ID, namespace.ActionUpdate)
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionRead)
 
-		if err != nil {
+		if !ok {
+			return
+		}
+
+		// Linting looks at every record of the namespace.
+		if scope.Restricted() {
 			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Permission denied",
+			})
+
+			return
+		}
+
+		lint, err := h.recordService.Lint(ctx, namespaceID)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -240,20 +455,35 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{
+		c.JSON(http.StatusOK, lint)
+	})
+
+	h.router.GET("/api/v1/namespaces/:namespaceID/rrsets/:name/:type", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
-				"message": "Permission denied",
+				"message": err.Error(),
 			})
 
 			return
 		}
 
-		recordID := c.Param("recordID")
+		namespaceID := c.Param("namespaceID")
 
-		var req RecordUpdateRequest
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionRead)
 
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if !ok {
+			return
+		}
+
+		recordType, err := ParseRecordType(c.Param("type"))
+
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -262,7 +492,11 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		record, err := h.recordService.Update(ctx, namespaceID, recordID, &req)
+		if !authorizeRecords(c, scope, &Record{Name: c.Param("name"), Type: recordType}) {
+			return
+		}
+
+		rrset, err := h.recordService.GetRRset(ctx, namespaceID, c.Param("name"), recordType)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -273,10 +507,10 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, record)
+		c.JSON(http.StatusOK, rrset)
 	})
 
-	h.router.DELETE("/api/v1/namespaces/:namespaceID/records/:recordID", func(c *gin.Context) {
+	h.router.PUT("/api/v1/namespaces/:namespaceID/rrsets/:name/:type", func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
@@ -291,12 +525,41 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
+		recordType, err := ParseRecordType(c.Param("type"))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		var req RRsetReplacementRequest
+
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
 		namespaceID := c.Param("namespaceID")
+		name := c.Param("name")
 
-		hasPermission, err := h.apiKeyAccessService.HasPermission(ctx, namespaceID, apiKey.ID, namespace.ActionDelete)
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionRead)
+
+		if !ok || !authorizeRecords(c, scope, &Record{Name: name, Type: recordType}) {
+			return
+		}
+
+		changes, err := h.recordService.RRsetReplacementChanges(ctx, namespaceID, name, recordType, &req)
 
 		if err != nil {
-			c.JSON(http.StatusForbidden, gin.H{
+			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -304,18 +567,63 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		if !hasPermission {
-			c.JSON(http.StatusForbidden, gin.H{
+		// Replacing an RRset takes the permissions of the changes it makes.
+		if _, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, changes.actions()...); !ok {
+			return
+		}
+
+		rrset, err := h.recordService.ReplaceRRset(ctx, namespaceID, name, recordType, changes, ActorTypeApiKey, apiKey.ID)
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, rrset)
+	})
+
+	h.router.DELETE("/api/v1/namespaces/:namespaceID/rrsets/:name/:type", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
-				"message": "Permission denied",
+				"message": err.Error(),
 			})
 
 			return
 		}
 
-		recordID := c.Param("recordID")
+		namespaceID := c.Param("namespaceID")
+
+		scope, ok := h.authorize(c, ctx, namespaceID, apiKey.ID, namespace.ActionDelete)
+
+		if !ok {
+			return
+		}
+
+		recordType, err := ParseRecordType(c.Param("type"))
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
 
-		record, err := h.recordService.Delete(ctx, namespaceID, recordID)
+		name := c.Param("name")
+
+		if !authorizeRecords(c, scope, &Record{Name: name, Type: recordType}) {
+			return
+		}
+
+		changes, err := h.recordService.RRsetDeletionChanges(ctx, namespaceID, name, recordType)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -326,6 +634,122 @@ func (h *RecordHandler) Register() {
 			return
 		}
 
-		c.JSON(http.StatusOK, record)
+		rrset, err := h.recordService.DeleteRRset(ctx, namespaceID, name, recordType, changes, ActorTypeApiKey, apiKey.ID)
+
+		if err != nil {
+			respondValidationError(c, err)
+
+			return
+		}
+
+		c.JSON(http.StatusOK, rrset)
 	})
 }
+
+// authorize checks the API key has an access to the namespace with the
+// permission of every given action, answering with 403 otherwise. It returns
+// the scope of the access.
+func (h *RecordHandler) authorize(c *gin.Context, ctx context.Context, namespaceID string, apiKeyID string, actions ...namespace.Action) (*namespace.Scope, bool) {
+	access, err := h.apiKeyAccessService.Get(ctx, namespaceID, apiKeyID)
+
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+
+		return nil, false
+	}
+
+	allowed := access != nil
+
+	for _, action := range actions {
+		allowed = allowed && access.Allows(action)
+	}
+
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Permission denied",
+		})
+
+		return nil, false
+	}
+
+	return &access.Scope, true
+}
+
+// authorizeRecord checks the record, and the record as the update would
+// leave it when set, are in scope, answering with 403 otherwise.
+func (h *RecordHandler) authorizeRecord(c *gin.Context, ctx context.Context, scope *namespace.Scope, namespaceID string, recordID string, update *RecordUpdateRequest) bool {
+	if !scope.Restricted() {
+		return true
+	}
+
+	record, err := h.recordService.Get(ctx, namespaceID, recordID)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+
+		return false
+	}
+
+	if update != nil {
+		return authorizeRecords(c, scope, record, update.apply(record))
+	}
+
+	return authorizeRecords(c, scope, record)
+}
+
+// authorizeChanges checks the records the changes touch are in scope,
+// answering with 403 otherwise. Changes to records that do not exist are left
+// to the validation of the changeset.
+func (h *RecordHandler) authorizeChanges(c *gin.Context, ctx context.Context, scope *namespace.Scope, namespaceID string, changes []RecordChange) bool {
+	if !scope.Restricted() {
+		return true
+	}
+
+	records := make([]*Record, 0, len(changes))
+
+	for _, change := range changes {
+		if change.Action == namespace.ActionCreate {
+			records = append(records, &Record{Name: change.Name, Type: change.Type})
+
+			continue
+		}
+
+		record, err := h.recordService.Get(ctx, namespaceID, change.RecordID)
+
+		if err != nil {
+			continue
+		}
+
+		records = append(records, record)
+
+		if change.Action == namespace.ActionUpdate {
+			records = append(records, change.updateRequest().apply(record))
+		}
+	}
+
+	return authorizeRecords(c, scope, records...)
+}
+
+// authorizeRecords checks every record is in scope, answering with 403
+// otherwise.
+func authorizeRecords(c *gin.Context, scope *namespace.Scope, records ...*Record) bool {
+	for _, record := range records {
+		if !scope.Matches(record.Name, string(record.Type)) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": fmt.Sprintf("record %s %s is out of the scope of the api key", record.Name, record.Type),
+			})
+
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,74 @@
+package dns
+
+import (
+	"context"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+)
+
+// RecordRevisionEmbeddedStore relies on revision IDs being ObjectIDs, whose
+// hex keys sort in creation order.
+type RecordRevisionEmbeddedStore struct {
+	revisions *embedded.Collection[RecordRevision]
+}
+
+func NewRecordRevisionEmbeddedStore(db embedded.DB) *RecordRevisionEmbeddedStore {
+	return &RecordRevisionEmbeddedStore{revisions: embedded.NewCollection[RecordRevision](db, "record_revisions")}
+}
+
+func (s *RecordRevisionEmbeddedStore) Insert(ctx context.Context, revision *RecordRevision) error {
+	return s.revisions.Put(ctx, revision.ID.Hex(), revision)
+}
+
+func (s *RecordRevisionEmbeddedStore) Latest(ctx context.Context, namespaceID string, recordID string) (*RecordRevision, error) {
+	revisions, err := s.ListByRecord(ctx, namespaceID, recordID, &pagination.Request{Size: 1})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(revisions.Items) == 0 {
+		return nil, storage.ErrNotFound
+	}
+
+	return revisions.Items[0], nil
+}
+
+func (s *RecordRevisionEmbeddedStore) ListByRecord(ctx context.Context, namespaceID string, recordID string, request *pagination.Request) (*pagination.Page[RecordRevision], error) {
+	return s.page(ctx, request, func(revision *RecordRevision) bool {
+		return revision.NamespaceID == namespaceID && revision.RecordID == recordID
+	})
+}
+
+func (s *RecordRevisionEmbeddedStore) ListByNamespace(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[RecordRevision], error) {
+	return s.page(ctx, request, func(revision *RecordRevision) bool {
+		return revision.NamespaceID == namespaceID
+	})
+}
+
+func (s *RecordRevisionEmbeddedStore) ListSince(ctx context.Context, namespaceID string, since time.Time) ([]*RecordRevision, error) {
+	return s.revisions.Find(ctx, func(revision *RecordRevision) bool {
+		return revision.NamespaceID == namespaceID && revision.CreatedAt.After(since)
+	})
+}
+
+func (s *RecordRevisionEmbeddedStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	_, err := s.revisions.DeleteWhere(ctx, func(revision *RecordRevision) bool {
+		return revision.NamespaceID == namespaceID
+	})
+
+	return err
+}
+
+func (s *RecordRevisionEmbeddedStore) page(ctx context.Context, request *pagination.Request, match func(revision *RecordRevision) bool) (*pagination.Page[RecordRevision], error) {
+	revisions, err := s.revisions.Find(ctx, match)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(revisions, request, newestFirst, revisionCursor), nil
+}
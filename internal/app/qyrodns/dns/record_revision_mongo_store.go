@@ -0,0 +1,98 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RecordRevisionMongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewRecordRevisionMongoStore(mongo *mongo.Collection) *RecordRevisionMongoStore {
+	return &RecordRevisionMongoStore{mongo: mongo}
+}
+
+func (s *RecordRevisionMongoStore) Insert(ctx context.Context, revision *RecordRevision) error {
+	_, err := s.mongo.InsertOne(ctx, revision)
+
+	return err
+}
+
+func (s *RecordRevisionMongoStore) Latest(ctx context.Context, namespaceID string, recordID string) (*RecordRevision, error) {
+	result := s.mongo.FindOne(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"record_id":    recordID,
+	}, options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}))
+
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, storage.ErrNotFound
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	revision := &RecordRevision{}
+
+	err := result.Decode(revision)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+func (s *RecordRevisionMongoStore) ListByRecord(ctx context.Context, namespaceID string, recordID string, request *pagination.Request) (*pagination.Page[RecordRevision], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"namespace_id": namespaceID,
+		"record_id":    recordID,
+	}, request, newestFirst, revisionCursor)
+}
+
+func (s *RecordRevisionMongoStore) ListByNamespace(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[RecordRevision], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"namespace_id": namespaceID,
+	}, request, newestFirst, revisionCursor)
+}
+
+func (s *RecordRevisionMongoStore) ListSince(ctx context.Context, namespaceID string, since time.Time) ([]*RecordRevision, error) {
+	return s.find(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"created_at":   bson.M{"$gt": since},
+	}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+}
+
+func (s *RecordRevisionMongoStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	_, err := s.mongo.DeleteMany(ctx, bson.M{
+		"namespace_id": namespaceID,
+	})
+
+	return err
+}
+
+func (s *RecordRevisionMongoStore) find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*RecordRevision, error) {
+	result, err := s.mongo.Find(ctx, filter, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*RecordRevision, 0)
+
+	err = result.All(ctx, &revisions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
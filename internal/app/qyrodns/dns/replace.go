@@ -0,0 +1,143 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+)
+
+// ReplaceValues rewrites the value of the records of active namespaces
+// matching the request. The changes of every namespace are validated before
+// any is applied, and then all applied in one transaction, each namespace
+// getting its own serial bump, so a failure leaves every namespace unchanged.
+func (s *RecordService) ReplaceValues(ctx context.Context, request *RecordReplacementRequest, actorType ActorType, actorID string) (*RecordReplacementResponse, error) {
+	records, err := s.searchAll(ctx, &RecordFilter{
+		Value: request.Value,
+		Type:  RecordType(strings.ToUpper(string(request.Type))),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	inactiveIDs, err := s.namespaceService.InactiveIDs(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &RecordReplacementResponse{
+		DryRun:     request.DryRun,
+		Namespaces: make([]*RecordReplacementResult, 0),
+	}
+
+	results := make(map[string]*RecordReplacementResult)
+
+	for _, record := range records {
+		if inactiveIDs[record.NamespaceID] {
+			continue
+		}
+
+		if len(request.NamespaceIDs) > 0 && !slices.Contains(request.NamespaceIDs, record.NamespaceID) {
+			continue
+		}
+
+		result, ok := results[record.NamespaceID]
+
+		if !ok {
+			result = &RecordReplacementResult{NamespaceID: record.NamespaceID, Changes: make([]RecordChange, 0)}
+			results[record.NamespaceID] = result
+			response.Namespaces = append(response.Namespaces, result)
+		}
+
+		result.Changes = append(result.Changes, RecordChange{
+			Action:   namespace.ActionUpdate,
+			RecordID: record.ID.Hex(),
+			Version:  &record.Version,
+			Name:     record.Name,
+			Type:     record.Type,
+			Value:    request.Replacement,
+			TTL:      record.TTL,
+			Class:    record.Class,
+		})
+
+		response.Replaced++
+	}
+
+	problems := make([]*ValidationProblem, 0)
+
+	for _, result := range response.Namespaces {
+		err := s.validateChanges(ctx, result.NamespaceID, result.Changes)
+
+		var validationError *ValidationError
+
+		if errors.As(err, &validationError) {
+			for _, problem := range validationError.Problems {
+				problem.Change = nil
+				problems = append(problems, problem)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	if request.DryRun {
+		return response, nil
+	}
+
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		for _, result := range response.Namespaces {
+			changes, err := s.ApplyChanges(ctx, result.NamespaceID, &RecordChangesRequest{Changes: result.Changes}, actorType, actorID)
+
+			if err != nil {
+				return fmt.Errorf("error replacing values in namespace %s: %w", result.NamespaceID, err)
+			}
+
+			result.Serial = changes.Serial
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// searchAll returns every record of every namespace matching the filter.
+func (s *RecordService) searchAll(ctx context.Context, filter *RecordFilter) ([]*Record, error) {
+	records := make([]*Record, 0)
+	request := &pagination.Request{Size: pagination.MaxSize}
+
+	for {
+		page, err := s.store.SearchAll(ctx, filter, request)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, page.Items...)
+
+		if page.NextCursor == "" {
+			return records, nil
+		}
+
+		last := filter.cursor(page.Items[len(page.Items)-1])
+		request.After = &last
+	}
+}
@@ -1,5 +1,11 @@
 package dns
 
+import (
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+)
+
 type RecordAdditionRequest struct {
 	Name  string      `json:"name" binding:"required"`
 	Type  RecordType  `json:"type" binding:"required"`
@@ -15,3 +21,89 @@ type RecordUpdateRequest struct {
 	TTL   uint32      `json:"ttl"`
 	Class RecordClass `json:"class"`
 }
+
+// RRsetReplacementRequest replaces every record of a name and type with one
+// record per value, all sharing the same TTL and class.
+type RRsetReplacementRequest struct {
+	Values []string    `json:"values" binding:"required,min=1,dive,required"`
+	TTL    uint32      `json:"ttl" binding:"required"`
+	Class  RecordClass `json:"class" binding:"required"`
+}
+
+// RecordSyncRequest holds the full desired set of records of a namespace.
+type RecordSyncRequest struct {
+	Records []RecordAdditionRequest `json:"records" binding:"dive"`
+	// DryRun only plans and validates the changes.
+	DryRun bool `json:"dry_run"`
+	// ProtectOthers keeps the records created by other actors than the caller
+	// even when they are not desired.
+	ProtectOthers bool `json:"protect_others"`
+	// scope, when set, leaves the records out of scope out of the sync.
+	scope *namespace.Scope
+}
+
+// RecordFilter narrows down and orders record listings. Empty fields match
+// every record.
+type RecordFilter struct {
+	// Name is matched case-insensitively and regardless of a trailing dot,
+	// exactly or as the NameMatch says.
+	Name      string    `form:"name"`
+	NameMatch NameMatch `form:"name_match" binding:"omitempty,oneof=exact prefix suffix contains"`
+	// Value is matched like Name, as the ValueMatch says.
+	Value        string     `form:"value"`
+	ValueMatch   NameMatch  `form:"value_match" binding:"omitempty,oneof=exact prefix suffix contains"`
+	Type         RecordType `form:"type"`
+	CreatorType  ActorType  `form:"creator_type" binding:"omitempty,oneof=admin apikey"`
+	CreatorID    string     `form:"creator_id"`
+	UpdatedSince time.Time  `form:"updated_since" time_format:"2006-01-02T15:04:05Z07:00"`
+	// Sort is a field to sort by, prefixed with - for a descending order.
+	// Records are in creation order otherwise.
+	Sort string `form:"sort" binding:"omitempty,oneof=name -name type -type created_at -created_at"`
+	// scope, when set, only matches the records in scope.
+	scope *namespace.Scope
+	// excludedNamespaceIDs leaves out the records of these namespaces.
+	excludedNamespaceIDs []string
+}
+
+type NameMatch string
+
+const (
+	NameMatchExact    NameMatch = "exact"
+	NameMatchPrefix   NameMatch = "prefix"
+	NameMatchSuffix   NameMatch = "suffix"
+	NameMatchContains NameMatch = "contains"
+)
+
+// RecordReplacementRequest replaces the value of the records whose value is
+// Value, matched as by a RecordFilter, with Replacement. Records can be
+// narrowed down to a type and to some namespaces.
+type RecordReplacementRequest struct {
+	Value        string     `json:"value" binding:"required"`
+	Type         RecordType `json:"type"`
+	NamespaceIDs []string   `json:"namespace_ids"`
+	Replacement  string     `json:"replacement" binding:"required"`
+	DryRun       bool       `json:"dry_run"`
+}
+
+type RollbackRequest struct {
+	Timestamp time.Time `json:"timestamp" binding:"required"`
+}
+
+type RecordChangesRequest struct {
+	Changes []RecordChange `json:"changes" binding:"required,min=1,dive"`
+}
+
+// RecordChange is one operation of a changeset. Creations take all the
+// record fields, updates the record ID and the fields to change, deletions
+// only the record ID.
+type RecordChange struct {
+	Action   namespace.Action `json:"action" binding:"required,oneof=create update delete"`
+	RecordID string           `json:"record_id"`
+	// Version, when set, is the version an updated or deleted record must be at.
+	Version *int64      `json:"version"`
+	Name    string      `json:"name"`
+	Type    RecordType  `json:"type"`
+	Value   string      `json:"value"`
+	TTL     uint32      `json:"ttl"`
+	Class   RecordClass `json:"class"`
+}
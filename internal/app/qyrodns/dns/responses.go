@@ -0,0 +1,61 @@
+package dns
+
+import "github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+
+// RecordSearchResult is a record found across namespaces, along with the
+// namespace serving it.
+type RecordSearchResult struct {
+	Record    *Record              `json:"record"`
+	Namespace *namespace.Namespace `json:"namespace"`
+}
+
+// RRset is the set of records of a name and type.
+type RRset struct {
+	Name    string      `json:"name"`
+	Type    RecordType  `json:"type"`
+	Class   RecordClass `json:"class"`
+	TTL     uint32      `json:"ttl"`
+	Records []*Record   `json:"records"`
+}
+
+// RecordSyncPlan is the changeset bringing a namespace to its desired state.
+type RecordSyncPlan struct {
+	DryRun bool `json:"dry_run"`
+	// Serial is the namespace serial after the plan was applied, unset for dry
+	// runs and empty plans.
+	Serial    uint32         `json:"serial,omitempty"`
+	Changes   []RecordChange `json:"changes"`
+	Unchanged int            `json:"unchanged"`
+	// Protected holds the records kept although not desired, as they were
+	// created by other actors.
+	Protected []*Record `json:"protected"`
+}
+
+type RecordReplacementResponse struct {
+	DryRun bool `json:"dry_run"`
+	// Replaced is the number of records whose value was, or would be, replaced.
+	Replaced   int                        `json:"replaced"`
+	Namespaces []*RecordReplacementResult `json:"namespaces"`
+}
+
+// RecordReplacementResult holds the changes made to a namespace by a
+// replacement.
+type RecordReplacementResult struct {
+	NamespaceID string `json:"namespace_id"`
+	// Serial is the namespace serial after the changes were applied, unset
+	// for dry runs.
+	Serial  uint32         `json:"serial,omitempty"`
+	Changes []RecordChange `json:"changes"`
+}
+
+type RecordLintResponse struct {
+	Valid    bool                 `json:"valid"`
+	Problems []*ValidationProblem `json:"problems"`
+}
+
+type RecordChangesResponse struct {
+	// Serial is the namespace serial after the changeset was applied.
+	Serial uint32 `json:"serial"`
+	// Records holds the resulting record of every change, in order.
+	Records []*Record `json:"records"`
+}
@@ -0,0 +1,148 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+)
+
+// GetRRset returns the records of the namespace with the given name and type.
+func (s *RecordService) GetRRset(ctx context.Context, namespaceID string, name string, recordType RecordType) (*RRset, error) {
+	records, err := s.rrsetRecords(ctx, namespaceID, name, recordType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("rrset not found")
+	}
+
+	return newRRset(name, recordType, records), nil
+}
+
+// RRsetReplacementChanges returns the changeset replacing the RRset with one
+// record per value, pinned to the versions read.
+func (s *RecordService) RRsetReplacementChanges(ctx context.Context, namespaceID string, name string, recordType RecordType, request *RRsetReplacementRequest) (*RecordChangesRequest, error) {
+	records, err := s.rrsetRecords(ctx, namespaceID, name, recordType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	desired := make([]RecordAdditionRequest, 0, len(request.Values))
+
+	for _, value := range request.Values {
+		desired = append(desired, RecordAdditionRequest{
+			Name:  name,
+			Type:  recordType,
+			Value: value,
+			TTL:   request.TTL,
+			Class: request.Class,
+		})
+	}
+
+	return &RecordChangesRequest{Changes: diffRecords(records, desired, nil).changes}, nil
+}
+
+// RRsetDeletionChanges returns the changeset moving every record of the RRset
+// to the trash.
+func (s *RecordService) RRsetDeletionChanges(ctx context.Context, namespaceID string, name string, recordType RecordType) (*RecordChangesRequest, error) {
+	records, err := s.rrsetRecords(ctx, namespaceID, name, recordType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("rrset not found")
+	}
+
+	changes := make([]RecordChange, 0, len(records))
+
+	for _, record := range records {
+		changes = append(changes, RecordChange{
+			Action:   namespace.ActionDelete,
+			RecordID: record.ID.Hex(),
+			Version:  &record.Version,
+		})
+	}
+
+	return &RecordChangesRequest{Changes: changes}, nil
+}
+
+// ReplaceRRset applies a changeset from RRsetReplacementChanges and returns
+// the resulting RRset.
+func (s *RecordService) ReplaceRRset(ctx context.Context, namespaceID string, name string, recordType RecordType, request *RecordChangesRequest, actorType ActorType, actorID string) (*RRset, error) {
+	if len(request.Changes) > 0 {
+		_, err := s.ApplyChanges(ctx, namespaceID, request, actorType, actorID)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetRRset(ctx, namespaceID, name, recordType)
+}
+
+// DeleteRRset applies a changeset from RRsetDeletionChanges and returns the
+// deleted RRset.
+func (s *RecordService) DeleteRRset(ctx context.Context, namespaceID string, name string, recordType RecordType, request *RecordChangesRequest, actorType ActorType, actorID string) (*RRset, error) {
+	response, err := s.ApplyChanges(ctx, namespaceID, request, actorType, actorID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newRRset(name, recordType, response.Records), nil
+}
+
+func (s *RecordService) rrsetRecords(ctx context.Context, namespaceID string, name string, recordType RecordType) ([]*Record, error) {
+	namespaceExists, err := s.namespaceService.Exists(ctx, namespaceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !namespaceExists {
+		return nil, fmt.Errorf("namespace not found")
+	}
+
+	records, err := s.store.List(ctx, namespaceID, 0, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rrset := make([]*Record, 0)
+
+	for _, record := range records {
+		if record.Type == recordType && normalizeName(record.Name) == normalizeName(name) {
+			rrset = append(rrset, record)
+		}
+	}
+
+	return rrset, nil
+}
+
+// newRRset groups the records of an RRset. Only records written before
+// validation existed can disagree on their TTL, which linting reports; the
+// lowest TTL is then reported, as resolvers would use.
+func newRRset(name string, recordType RecordType, records []*Record) *RRset {
+	rrset := &RRset{
+		Name:    name,
+		Type:    recordType,
+		Records: records,
+	}
+
+	for _, record := range records {
+		if rrset.TTL == 0 || record.TTL < rrset.TTL {
+			rrset.TTL = record.TTL
+		}
+
+		rrset.Class = record.Class
+	}
+
+	return rrset
+}
@@ -4,23 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type RecordService struct {
-	mongo            *mongo.Collection
+	store            RecordStore
+	revisionStore    RecordRevisionStore
+	transactor       storage.Transactor
 	namespaceService *namespace.Service
 }
 
-func NewRecordService(mongo *mongo.Collection, namespaceService *namespace.Service) *RecordService {
-	return &RecordService{mongo: mongo, namespaceService: namespaceService}
+func NewRecordService(store RecordStore, revisionStore RecordRevisionStore, transactor storage.Transactor, namespaceService *namespace.Service) *RecordService {
+	return &RecordService{store: store, revisionStore: revisionStore, transactor: transactor, namespaceService: namespaceService}
 }
 
 func (s *RecordService) Add(ctx context.Context, namespaceID string, request *RecordAdditionRequest, creatorType ActorType, creatorID string) (*Record, error) {
@@ -34,21 +38,19 @@ func (s *RecordService) Add(ctx context.Context, namespaceID string, request *Re
 		return nil, fmt.Errorf("namespace not found")
 	}
 
-	record := &Record{
-		ID:          primitive.NewObjectID(),
-		NamespaceID: namespaceID,
-		Name:        request.Name,
-		Type:        request.Type,
-		Value:       request.Value,
-		TTL:         request.TTL,
-		Class:       request.Class,
-		CreatorType: creatorType,
-		CreatorID:   creatorID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
+	var record *Record
+
+	_, err = s.change(ctx, namespaceID, func(ctx context.Context) error {
+		err := s.validateChange(ctx, namespaceID, request.change())
 
-	_, err = s.mongo.InsertOne(ctx, record)
+		if err != nil {
+			return err
+		}
+
+		record, err = s.add(ctx, namespaceID, request, creatorType, creatorID)
+
+		return err
+	})
 
 	if err != nil {
 		return nil, err
@@ -57,24 +59,55 @@ func (s *RecordService) Add(ctx context.Context, namespaceID string, request *Re
 	return record, nil
 }
 
-func (s *RecordService) List(ctx context.Context, namespaceID string, page int64, size int64) ([]*Record, error) {
-	result, err := s.mongo.Find(ctx, bson.M{
-		"namespace_id": namespaceID,
-	}, options.Find().SetSkip(page*size).SetLimit(size))
+func (s *RecordService) List(ctx context.Context, namespaceID string, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error) {
+	filter.Type = RecordType(strings.ToUpper(string(filter.Type)))
+
+	return s.store.Search(ctx, namespaceID, filter, request)
+}
+
+// Search lists the records of every active namespace matching the filter,
+// each with its namespace.
+func (s *RecordService) Search(ctx context.Context, filter *RecordFilter, request *pagination.Request) (*pagination.Page[RecordSearchResult], error) {
+	filter.Type = RecordType(strings.ToUpper(string(filter.Type)))
+
+	inactiveIDs, err := s.namespaceService.InactiveIDs(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	records := make([]*Record, 0)
+	filter.excludedNamespaceIDs = slices.Collect(maps.Keys(inactiveIDs))
 
-	err = result.All(ctx, &records)
+	page, err := s.store.SearchAll(ctx, filter, request)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return records, nil
+	return pagination.Map(page, func(records []*Record) ([]*RecordSearchResult, error) {
+		namespaces := make(map[string]*namespace.Namespace)
+		results := make([]*RecordSearchResult, 0, len(records))
+
+		for _, record := range records {
+			ns, ok := namespaces[record.NamespaceID]
+
+			if !ok {
+				var err error
+
+				ns, err = s.namespaceService.Get(ctx, record.NamespaceID)
+
+				if err != nil {
+					return nil, err
+				}
+
+				namespaces[record.NamespaceID] = ns
+			}
+
+			results = append(results, &RecordSearchResult{Record: record, Namespace: ns})
+		}
+
+		return results, nil
+	})
 }
 
 func (s *RecordService) Get(ctx context.Context, namespaceID string, recordID string) (*Record, error) {
@@ -84,22 +117,35 @@ func (s *RecordService) Get(ctx context.Context, namespaceID string, recordID st
 		return nil, err
 	}
 
-	result := s.mongo.FindOne(ctx, bson.M{
-		"_id":          id,
-		"namespace_id": namespaceID,
-	})
+	record, err := s.store.Get(ctx, namespaceID, id)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("record not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if err != nil {
+		return nil, err
 	}
 
-	record := &Record{}
+	return record, nil
+}
+
+// Update changes the record. When ifMatch is set, the record must be at that
+// version.
+func (s *RecordService) Update(ctx context.Context, namespaceID string, recordID string, request *RecordUpdateRequest, ifMatch *int64, actorType ActorType, actorID string) (*Record, error) {
+	var record *Record
 
-	err = result.Decode(record)
+	_, err := s.change(ctx, namespaceID, func(ctx context.Context) error {
+		err := s.validateChange(ctx, namespaceID, request.change(recordID, ifMatch))
+
+		if err != nil {
+			return err
+		}
+
+		record, err = s.update(ctx, namespaceID, recordID, request, ifMatch, actorType, actorID)
+
+		return err
+	})
 
 	if err != nil {
 		return nil, err
@@ -108,92 +154,208 @@ func (s *RecordService) Get(ctx context.Context, namespaceID string, recordID st
 	return record, nil
 }
 
-func (s *RecordService) Update(ctx context.Context, namespaceID string, recordID string, request *RecordUpdateRequest) (*Record, error) {
-	id, err := primitive.ObjectIDFromHex(recordID)
+// Delete moves the record to the trash, where it is kept until restored or
+// purged. When ifMatch is set, the record must be at that version.
+func (s *RecordService) Delete(ctx context.Context, namespaceID string, recordID string, ifMatch *int64, actorType ActorType, actorID string) (*Record, error) {
+	var record *Record
+
+	_, err := s.change(ctx, namespaceID, func(ctx context.Context) error {
+		var err error
+
+		record, err = s.delete(ctx, namespaceID, recordID, ifMatch, actorType, actorID)
+
+		return err
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	fields := bson.M{
-		"updated_at": time.Now(),
-	}
+	return record, nil
+}
 
-	if request.Name != "" {
-		fields["name"] = request.Name
-	}
+func (s *RecordService) ListTrash(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[Record], error) {
+	return s.store.ListTrash(ctx, namespaceID, request)
+}
 
-	if request.Value != "" {
-		fields["value"] = request.Value
-	}
+func (s *RecordService) Restore(ctx context.Context, namespaceID string, recordID string, actorType ActorType, actorID string) (*Record, error) {
+	id, err := primitive.ObjectIDFromHex(recordID)
 
-	if request.Type != "" {
-		fields["type"] = request.Type
+	if err != nil {
+		return nil, err
 	}
 
-	if request.Class != "" {
-		fields["class"] = request.Class
-	}
+	var record *Record
+
+	_, err = s.change(ctx, namespaceID, func(ctx context.Context) error {
+		var err error
+
+		record, err = s.store.Restore(ctx, namespaceID, id)
+
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("record not found in trash")
+		}
+
+		if err != nil {
+			return err
+		}
+
+		before, err := s.latestState(ctx, namespaceID, recordID)
+
+		if err != nil {
+			return err
+		}
+
+		return s.addRevision(ctx, RevisionActionRestore, actorType, actorID, before, record)
+	})
 
-	if request.TTL != 0 {
-		fields["ttl"] = request.TTL
+	if err != nil {
+		return nil, err
 	}
 
-	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
-		"_id":          id,
-		"namespace_id": namespaceID,
-	}, bson.M{
-		"$set": fields,
-	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	return record, nil
+}
+
+func (s *RecordService) History(ctx context.Context, namespaceID string, recordID string, request *pagination.Request) (*pagination.Page[RecordRevision], error) {
+	return s.revisionStore.ListByRecord(ctx, namespaceID, recordID, request)
+}
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
-		return nil, fmt.Errorf("record not found")
+func (s *RecordService) NamespaceHistory(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[RecordRevision], error) {
+	return s.revisionStore.ListByNamespace(ctx, namespaceID, request)
+}
+
+// Rollback brings the records of the namespace back to their state at the
+// given time, using the first revision of each record changed since then.
+// Records created since then are moved to the trash. Every change made is
+// itself recorded as a revision, so a rollback can be rolled back too. A
+// record changed during the rollback fails it with storage.ErrConflict.
+func (s *RecordService) Rollback(ctx context.Context, namespaceID string, at time.Time, actorType ActorType, actorID string) ([]*RecordRevision, error) {
+	namespaceExists, err := s.namespaceService.Exists(ctx, namespaceID)
+
+	if err != nil {
+		return nil, err
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if !namespaceExists {
+		return nil, fmt.Errorf("namespace not found")
 	}
 
-	record := &Record{}
+	var applied []*RecordRevision
+
+	_, err = s.change(ctx, namespaceID, func(ctx context.Context) error {
+		var err error
+
+		applied, err = s.rollback(ctx, namespaceID, at, actorType, actorID)
 
-	err = result.Decode(record)
+		return err
+	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	return record, nil
+	return applied, nil
 }
 
-func (s *RecordService) Delete(ctx context.Context, namespaceID string, recordID string) (*Record, error) {
-	id, err := primitive.ObjectIDFromHex(recordID)
+// rollback reads the revisions in the transaction of Rollback, so that every
+// record is reverted from the version of its latest revision.
+func (s *RecordService) rollback(ctx context.Context, namespaceID string, at time.Time, actorType ActorType, actorID string) ([]*RecordRevision, error) {
+	revisions, err := s.revisionStore.ListSince(ctx, namespaceID, at)
 
 	if err != nil {
 		return nil, err
 	}
 
-	result := s.mongo.FindOneAndDelete(ctx, bson.M{
-		"_id":          id,
-		"namespace_id": namespaceID,
-	})
+	recordIDs := make([]string, 0)
+	targets := make(map[string]*Record)
+	currents := make(map[string]*Record)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
-		return nil, fmt.Errorf("record not found")
+	for _, revision := range revisions {
+		if _, ok := targets[revision.RecordID]; !ok {
+			recordIDs = append(recordIDs, revision.RecordID)
+			targets[revision.RecordID] = revision.Before
+		}
+
+		currents[revision.RecordID] = revision.After
+	}
+
+	applied := make([]*RecordRevision, 0)
+
+	for _, recordID := range recordIDs {
+		current := currents[recordID]
+
+		after, err := s.revert(ctx, targets[recordID], current)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if after == nil {
+			continue
+		}
+
+		revision, err := s.newRevision(ctx, RevisionActionRollback, actorType, actorID, current, after)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.revisionStore.Insert(ctx, revision)
+
+		if err != nil {
+			return nil, err
+		}
+
+		applied = append(applied, revision)
+	}
+
+	return applied, nil
+}
+
+// revert brings a record from its current state back to the target one, and
+// returns nil when there is nothing to change. It fails with
+// storage.ErrConflict when the record is not at its current version anymore.
+func (s *RecordService) revert(ctx context.Context, target *Record, current *Record) (*Record, error) {
+	if target == nil {
+		if current == nil || current.DeletedAt != nil {
+			return nil, nil
+		}
+
+		after, err := s.store.Trash(ctx, current.NamespaceID, current.ID, current.Version)
+
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil
+		}
+
+		return after, err
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	after := *target
+	after.UpdatedAt = time.Now()
+
+	if current == nil {
+		return &after, s.store.Insert(ctx, &after)
 	}
 
-	record := &Record{}
+	after.Version = current.Version + 1
 
-	err = result.Decode(record)
+	err := s.store.Replace(ctx, &after, current.Version)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		// The record was purged from the trash since.
+		err = s.store.Insert(ctx, &after)
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	return record, nil
+	return &after, nil
+}
+
+// PurgeTrash permanently removes the records trashed before the given time.
+func (s *RecordService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return s.store.PurgeTrash(ctx, deletedBefore)
 }
 
 func (s *RecordService) Query(ctx context.Context, name string, recordType RecordType) ([]*Record, error) {
@@ -206,32 +368,328 @@ func (s *RecordService) Query(ctx context.Context, name string, recordType Recor
 		queryNames = append(queryNames, name)
 	}
 
-	result, err := s.mongo.Find(ctx, bson.M{
-		"name": bson.M{
-			"$in": queryNames,
-		},
-		"type": recordType,
-	})
+	records, err := s.store.Query(ctx, queryNames, recordType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.served(ctx, records)
+}
+
+func (s *RecordService) ListServed(ctx context.Context) ([]*Record, error) {
+	records, err := s.store.ListAll(ctx)
 
 	if err != nil {
 		return nil, err
 	}
 
-	records := make([]*Record, 0)
+	return s.served(ctx, records)
+}
 
-	err = result.All(ctx, &records)
+func (s *RecordService) served(ctx context.Context, records []*Record) ([]*Record, error) {
+	records, err := s.withoutDeletedNamespaces(ctx, records)
 
 	if err != nil {
 		return nil, err
 	}
 
+	return s.withSerials(ctx, records)
+}
+
+// withSerials replaces the serial of SOA records with the serial of their
+// namespace, once the namespace has a higher one.
+func (s *RecordService) withSerials(ctx context.Context, records []*Record) ([]*Record, error) {
+	serials := make(map[string]uint32)
+
+	for i, record := range records {
+		if record.Type != RecordTypeSOA {
+			continue
+		}
+
+		serial, ok := serials[record.NamespaceID]
+
+		if !ok {
+			namespace, err := s.namespaceService.Get(ctx, record.NamespaceID)
+
+			if err != nil {
+				return nil, err
+			}
+
+			serial = namespace.Serial
+			serials[record.NamespaceID] = serial
+		}
+
+		current, ok := soaRecordSerial(record)
+
+		if !ok || current >= serial {
+			continue
+		}
+
+		fields := strings.Fields(record.Value)
+		fields[2] = strconv.FormatUint(uint64(serial), 10)
+
+		served := *record
+		served.Value = strings.Join(fields, " ")
+		records[i] = &served
+	}
+
 	return records, nil
 }
 
+// withoutDeletedNamespaces drops the records of namespaces in the trash or
+// being deleted.
+func (s *RecordService) withoutDeletedNamespaces(ctx context.Context, records []*Record) ([]*Record, error) {
+	inactiveIDs, err := s.namespaceService.InactiveIDs(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inactiveIDs) == 0 {
+		return records, nil
+	}
+
+	served := make([]*Record, 0, len(records))
+
+	for _, record := range records {
+		if !inactiveIDs[record.NamespaceID] {
+			served = append(served, record)
+		}
+	}
+
+	return served, nil
+}
+
 func (s *RecordService) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
-	_, err := s.mongo.DeleteMany(ctx, bson.M{
-		"namespace_id": namespaceID,
+	err := s.store.DeleteByNamespaceID(ctx, namespaceID)
+
+	if err != nil {
+		return err
+	}
+
+	return s.revisionStore.DeleteByNamespaceID(ctx, namespaceID)
+}
+
+// change runs fn and bumps the serial of the namespace in one transaction,
+// so that resolvers never observe part of a change. The serial is bumped past
+// the serials written in the SOA records of the namespace, so that it never
+// goes back from a serial set by the operator.
+func (s *RecordService) change(ctx context.Context, namespaceID string, fn func(ctx context.Context) error) (uint32, error) {
+	var serial uint32
+
+	err := s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		floor, err := s.soaSerial(ctx, namespaceID)
+
+		if err != nil {
+			return err
+		}
+
+		serial, err = s.namespaceService.BumpSerial(ctx, namespaceID, floor)
+
+		return err
 	})
 
-	return err
+	return serial, err
+}
+
+// soaSerial returns the highest serial written in the SOA records of the
+// namespace, or 0 when it has none.
+func (s *RecordService) soaSerial(ctx context.Context, namespaceID string) (uint32, error) {
+	page, err := s.store.Search(ctx, namespaceID, &RecordFilter{Type: RecordTypeSOA}, &pagination.Request{Size: pagination.MaxSize})
+
+	if err != nil {
+		return 0, err
+	}
+
+	var highest uint32
+
+	for _, record := range page.Items {
+		if serial, ok := soaRecordSerial(record); ok && serial > highest {
+			highest = serial
+		}
+	}
+
+	return highest, nil
+}
+
+func (s *RecordService) add(ctx context.Context, namespaceID string, request *RecordAdditionRequest, creatorType ActorType, creatorID string) (*Record, error) {
+	record := &Record{
+		ID:          primitive.NewObjectID(),
+		NamespaceID: namespaceID,
+		Name:        request.Name,
+		Type:        request.Type,
+		Value:       request.Value,
+		TTL:         request.TTL,
+		Class:       request.Class,
+		CreatorType: creatorType,
+		CreatorID:   creatorID,
+		CreatedAt:   time.Now(),
+		Version:     1,
+		UpdatedAt:   time.Now(),
+	}
+
+	err := s.store.Insert(ctx, record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.addRevision(ctx, RevisionActionCreate, creatorType, creatorID, nil, record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (s *RecordService) update(ctx context.Context, namespaceID string, recordID string, request *RecordUpdateRequest, ifMatch *int64, actorType ActorType, actorID string) (*Record, error) {
+	before, err := s.getAt(ctx, namespaceID, recordID, ifMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.store.Update(ctx, namespaceID, before.ID, request, before.Version)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("record not found")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.addRevision(ctx, RevisionActionUpdate, actorType, actorID, before, record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (s *RecordService) delete(ctx context.Context, namespaceID string, recordID string, ifMatch *int64, actorType ActorType, actorID string) (*Record, error) {
+	before, err := s.getAt(ctx, namespaceID, recordID, ifMatch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.store.Trash(ctx, namespaceID, before.ID, before.Version)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("record not found")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.addRevision(ctx, RevisionActionDelete, actorType, actorID, before, record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// getAt returns the record, checking it is at the version required by
+// ifMatch when set.
+func (s *RecordService) getAt(ctx context.Context, namespaceID string, recordID string, ifMatch *int64) (*Record, error) {
+	record, err := s.Get(ctx, namespaceID, recordID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if ifMatch != nil && *ifMatch != record.Version {
+		return nil, fmt.Errorf("record is at version %d: %w", record.Version, storage.ErrConflict)
+	}
+
+	return record, nil
+}
+
+func (s *RecordService) addRevision(ctx context.Context, action RevisionAction, actorType ActorType, actorID string, before *Record, after *Record) error {
+	revision, err := s.newRevision(ctx, action, actorType, actorID, before, after)
+
+	if err != nil {
+		return err
+	}
+
+	return s.revisionStore.Insert(ctx, revision)
+}
+
+func (s *RecordService) newRevision(ctx context.Context, action RevisionAction, actorType ActorType, actorID string, before *Record, after *Record) (*RecordRevision, error) {
+	record := after
+
+	if record == nil {
+		record = before
+	}
+
+	revision := &RecordRevision{
+		ID:          primitive.NewObjectID(),
+		NamespaceID: record.NamespaceID,
+		RecordID:    record.ID.Hex(),
+		Version:     1,
+		Action:      action,
+		ActorType:   actorType,
+		ActorID:     actorID,
+		Before:      before,
+		After:       after,
+		CreatedAt:   time.Now(),
+	}
+
+	latest, err := s.revisionStore.Latest(ctx, revision.NamespaceID, revision.RecordID)
+
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	if latest != nil {
+		revision.Version = latest.Version + 1
+	}
+
+	return revision, nil
+}
+
+// latestState returns the record as of its latest revision, or nil for a
+// record changed before history was kept.
+func (s *RecordService) latestState(ctx context.Context, namespaceID string, recordID string) (*Record, error) {
+	latest, err := s.revisionStore.Latest(ctx, namespaceID, recordID)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return latest.After, nil
+}
+
+// soaRecordSerial returns the serial written in the value of the SOA record.
+func soaRecordSerial(record *Record) (uint32, bool) {
+	fields := strings.Fields(record.Value)
+
+	if len(fields) < 3 {
+		return 0, false
+	}
+
+	serial, err := strconv.ParseUint(fields[2], 10, 32)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return uint32(serial), true
 }
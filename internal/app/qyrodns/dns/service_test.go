@@ -0,0 +1,171 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// failingRecordStore fails the updates of the records of a namespace.
+type failingRecordStore struct {
+	RecordStore
+	namespaceID string
+}
+
+var errUpdateFailed = errors.New("update failed")
+
+func (s *failingRecordStore) Update(ctx context.Context, namespaceID string, id primitive.ObjectID, request *RecordUpdateRequest, version int64) (*Record, error) {
+	if namespaceID == s.namespaceID {
+		return nil, errUpdateFailed
+	}
+
+	return s.RecordStore.Update(ctx, namespaceID, id, request, version)
+}
+
+// racingRevisionStore updates a record right after the revisions are listed,
+// as if another change was made between the read and the writes.
+type racingRevisionStore struct {
+	RecordRevisionStore
+	race func(ctx context.Context) error
+}
+
+func (s *racingRevisionStore) ListSince(ctx context.Context, namespaceID string, since time.Time) ([]*RecordRevision, error) {
+	revisions, err := s.RecordRevisionStore.ListSince(ctx, namespaceID, since)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, s.race(ctx)
+}
+
+func TestRollbackConflictsWithConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	db := embedded.NewMemoryDB()
+
+	recordStore := NewRecordEmbeddedStore(db)
+	revisionStore := &racingRevisionStore{RecordRevisionStore: NewRecordRevisionEmbeddedStore(db)}
+
+	namespaceService := namespace.NewService(namespace.NewEmbeddedStore(db))
+	recordService := NewRecordService(recordStore, revisionStore, db, namespaceService)
+
+	created, err := namespaceService.Create(ctx, &namespace.CreationRequest{Name: "example"}, "admin")
+
+	if err != nil {
+		t.Fatalf("error creating namespace: %v", err)
+	}
+
+	namespaceID := created.ID.Hex()
+
+	record, err := recordService.Add(ctx, namespaceID, &RecordAdditionRequest{Name: "example.com", Type: RecordTypeA, Value: "10.0.0.1", TTL: 60, Class: "IN"}, ActorTypeAdmin, "admin")
+
+	if err != nil {
+		t.Fatalf("error adding record: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	checkpoint := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	record, err = recordService.Update(ctx, namespaceID, record.ID.Hex(), &RecordUpdateRequest{Value: "10.0.0.2"}, nil, ActorTypeAdmin, "admin")
+
+	if err != nil {
+		t.Fatalf("error updating record: %v", err)
+	}
+
+	revisionStore.race = func(ctx context.Context) error {
+		_, err := recordStore.Update(ctx, namespaceID, record.ID, &RecordUpdateRequest{Value: "10.0.0.3"}, record.Version)
+
+		return err
+	}
+
+	_, err = recordService.Rollback(ctx, namespaceID, checkpoint, ActorTypeAdmin, "admin")
+
+	if !errors.Is(err, storage.ErrConflict) {
+		t.Fatalf("expected the rollback to conflict, got %v", err)
+	}
+
+	revisionStore.race = func(ctx context.Context) error {
+		return nil
+	}
+
+	current, err := recordService.Get(ctx, namespaceID, record.ID.Hex())
+
+	if err != nil || current.Value != "10.0.0.2" {
+		t.Fatalf("expected the record to be left unchanged, got %+v, %v", current, err)
+	}
+
+	_, err = recordService.Rollback(ctx, namespaceID, checkpoint, ActorTypeAdmin, "admin")
+
+	if err != nil {
+		t.Fatalf("error rolling back: %v", err)
+	}
+
+	current, err = recordService.Get(ctx, namespaceID, record.ID.Hex())
+
+	if err != nil || current.Value != "10.0.0.1" || current.Version != record.Version+1 {
+		t.Fatalf("expected the value from before the update, got %+v, %v", current, err)
+	}
+}
+
+func TestFailedReplacementChangesNoNamespace(t *testing.T) {
+	ctx := context.Background()
+	db := embedded.NewMemoryDB()
+
+	recordStore := &failingRecordStore{RecordStore: NewRecordEmbeddedStore(db)}
+
+	namespaceService := namespace.NewService(namespace.NewEmbeddedStore(db))
+	recordService := NewRecordService(recordStore, NewRecordRevisionEmbeddedStore(db), db, namespaceService)
+
+	namespaces := make([]*namespace.Namespace, 0)
+
+	for _, name := range []string{"first", "second"} {
+		created, err := namespaceService.Create(ctx, &namespace.CreationRequest{Name: name}, "admin")
+
+		if err != nil {
+			t.Fatalf("error creating namespace: %v", err)
+		}
+
+		_, err = recordService.Add(ctx, created.ID.Hex(), &RecordAdditionRequest{Name: name + ".com", Type: RecordTypeA, Value: "192.0.2.1", TTL: 60, Class: "IN"}, ActorTypeAdmin, "admin")
+
+		if err != nil {
+			t.Fatalf("error adding record: %v", err)
+		}
+
+		created, err = namespaceService.Get(ctx, created.ID.Hex())
+
+		if err != nil {
+			t.Fatalf("error getting namespace: %v", err)
+		}
+
+		namespaces = append(namespaces, created)
+	}
+
+	recordStore.namespaceID = namespaces[1].ID.Hex()
+
+	_, err := recordService.ReplaceValues(ctx, &RecordReplacementRequest{Value: "192.0.2.1", Replacement: "198.51.100.1"}, ActorTypeAdmin, "admin")
+
+	if !errors.Is(err, errUpdateFailed) {
+		t.Fatalf("expected the replacement to fail in the second namespace, got %v", err)
+	}
+
+	for _, checked := range namespaces {
+		records, err := recordService.Query(ctx, checked.Name+".com", RecordTypeA)
+
+		if err != nil || len(records) != 1 || records[0].Value != "192.0.2.1" {
+			t.Fatalf("expected the record of namespace %s to keep its value, got %v, %v", checked.Name, records, err)
+		}
+
+		current, err := namespaceService.Get(ctx, checked.ID.Hex())
+
+		if err != nil || current.Serial != checked.Serial {
+			t.Fatalf("expected the serial of namespace %s to be kept, got %+v, %v", checked.Name, current, err)
+		}
+	}
+}
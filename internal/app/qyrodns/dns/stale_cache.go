@@ -0,0 +1,237 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot is a point-in-time copy of every served record. It is what the DNS
+// path falls back to while the datastore is unavailable.
+type Snapshot struct {
+	TakenAt time.Time `json:"taken_at"`
+	Records []*Record `json:"records"`
+}
+
+type staleCacheKey struct {
+	name       string
+	recordType RecordType
+}
+
+// StaleCache keeps the last known good copy of the records, refreshes it
+// periodically and optionally persists it to a local file so that it survives
+// restarts during a datastore outage.
+type StaleCache struct {
+	recordService   *RecordService
+	path            string
+	refreshInterval time.Duration
+	maxStaleness    time.Duration
+
+	mu      sync.RWMutex
+	takenAt time.Time
+	records map[staleCacheKey][]*Record
+
+	degraded      atomic.Bool
+	degradedSince atomic.Int64
+}
+
+func NewStaleCache(recordService *RecordService, path string, refreshInterval time.Duration, maxStaleness time.Duration) (*StaleCache, error) {
+	if refreshInterval <= 0 {
+		return nil, fmt.Errorf("DNS snapshot interval must be positive")
+	}
+
+	return &StaleCache{
+		recordService:   recordService,
+		path:            path,
+		refreshInterval: refreshInterval,
+		maxStaleness:    maxStaleness,
+		records:         make(map[staleCacheKey][]*Record),
+	}, nil
+}
+
+// Load reads the snapshot file, if one is configured and present.
+func (c *StaleCache) Load() error {
+	if c.path == "" {
+		return fmt.Errorf("snapshot path is not configured")
+	}
+
+	data, err := os.ReadFile(c.path)
+
+	if err != nil {
+		return err
+	}
+
+	snapshot := &Snapshot{}
+
+	err = json.Unmarshal(data, snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	c.apply(snapshot)
+
+	return nil
+}
+
+// Refresh replaces the in-memory copy with the current contents of the
+// datastore and persists it when a snapshot path is configured.
+func (c *StaleCache) Refresh(ctx context.Context) error {
+	records, err := c.recordService.ListServed(ctx)
+
+	if err != nil {
+		c.MarkDegraded()
+		return err
+	}
+
+	snapshot := &Snapshot{
+		TakenAt: time.Now(),
+		Records: records,
+	}
+
+	c.apply(snapshot)
+	c.MarkHealthy()
+
+	if c.path == "" {
+		return nil
+	}
+
+	return c.save(snapshot)
+}
+
+// Run refreshes the cache right away and then every refresh interval until
+// the context is done.
+func (c *StaleCache) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		refreshCtx, cancel := context.WithTimeout(ctx, c.refreshInterval)
+		err := c.Refresh(refreshCtx)
+		cancel()
+
+		if err != nil {
+			log.Printf("error refreshing DNS snapshot: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Query answers from the snapshot. The second return value is false when there
+// is no snapshot or it is older than the configured maximum staleness.
+func (c *StaleCache) Query(name string, recordType RecordType) ([]*Record, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.takenAt.IsZero() || time.Since(c.takenAt) > c.maxStaleness {
+		return nil, false
+	}
+
+	records := c.records[staleCacheKey{name: strings.TrimSuffix(name, "."), recordType: recordType}]
+
+	return records, true
+}
+
+func (c *StaleCache) MarkDegraded() {
+	if c.degraded.CompareAndSwap(false, true) {
+		c.degradedSince.Store(time.Now().Unix())
+		log.Printf("datastore unavailable, serving DNS answers from snapshot")
+	}
+}
+
+func (c *StaleCache) MarkHealthy() {
+	if c.degraded.CompareAndSwap(true, false) {
+		log.Printf("datastore available again, leaving degraded mode")
+	}
+}
+
+func (c *StaleCache) Degraded() bool {
+	return c.degraded.Load()
+}
+
+func (c *StaleCache) Status() *StaleCacheStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := &StaleCacheStatus{
+		Degraded: c.degraded.Load(),
+	}
+
+	if !c.takenAt.IsZero() {
+		takenAt := c.takenAt
+		status.SnapshotTakenAt = &takenAt
+		status.SnapshotAgeSeconds = int64(time.Since(c.takenAt).Seconds())
+	}
+
+	if status.Degraded {
+		degradedSince := time.Unix(c.degradedSince.Load(), 0)
+		status.DegradedSince = &degradedSince
+	}
+
+	return status
+}
+
+func (c *StaleCache) apply(snapshot *Snapshot) {
+	records := make(map[staleCacheKey][]*Record)
+
+	for _, record := range snapshot.Records {
+		key := staleCacheKey{name: strings.TrimSuffix(record.Name, "."), recordType: record.Type}
+		records[key] = append(records[key], record)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.takenAt = snapshot.TakenAt
+	c.records = records
+}
+
+func (c *StaleCache) save(snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+type StaleCacheStatus struct {
+	Degraded           bool       `json:"degraded"`
+	DegradedSince      *time.Time `json:"degraded_since,omitempty"`
+	SnapshotTakenAt    *time.Time `json:"snapshot_taken_at,omitempty"`
+	SnapshotAgeSeconds int64      `json:"snapshot_age_seconds"`
+}
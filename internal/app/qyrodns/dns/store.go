@@ -0,0 +1,58 @@
+package dns
+
+import (
+	"context"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RecordStore interface {
+	Insert(ctx context.Context, record *Record) error
+	List(ctx context.Context, namespaceID string, page int64, size int64) ([]*Record, error)
+	Search(ctx context.Context, namespaceID string, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error)
+	// SearchAll searches the records of every namespace.
+	SearchAll(ctx context.Context, filter *RecordFilter, request *pagination.Request) (*pagination.Page[Record], error)
+	ListAll(ctx context.Context) ([]*Record, error)
+	Get(ctx context.Context, namespaceID string, id primitive.ObjectID) (*Record, error)
+	// Update and Trash only change the record at the given version, and fail
+	// with storage.ErrConflict otherwise.
+	Update(ctx context.Context, namespaceID string, id primitive.ObjectID, request *RecordUpdateRequest, version int64) (*Record, error)
+	Query(ctx context.Context, names []string, recordType RecordType) ([]*Record, error)
+	DeleteByNamespaceID(ctx context.Context, namespaceID string) error
+
+	// Records in the trash are left out by all the methods above.
+
+	Trash(ctx context.Context, namespaceID string, id primitive.ObjectID, version int64) (*Record, error)
+	ListTrash(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[Record], error)
+	Restore(ctx context.Context, namespaceID string, id primitive.ObjectID) (*Record, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	// Replace writes the record as is over the record at the given version,
+	// live or in the trash. It fails with storage.ErrConflict when the record
+	// is at another version, and with storage.ErrNotFound once it is purged.
+	Replace(ctx context.Context, record *Record, version int64) error
+}
+
+type RecordRevisionStore interface {
+	Insert(ctx context.Context, revision *RecordRevision) error
+	Latest(ctx context.Context, namespaceID string, recordID string) (*RecordRevision, error)
+	// ListByRecord and ListByNamespace return the newest revisions first.
+	ListByRecord(ctx context.Context, namespaceID string, recordID string, request *pagination.Request) (*pagination.Page[RecordRevision], error)
+	ListByNamespace(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[RecordRevision], error)
+	// ListSince returns the revisions made after the given time, oldest first.
+	ListSince(ctx context.Context, namespaceID string, since time.Time) ([]*RecordRevision, error)
+	DeleteByNamespaceID(ctx context.Context, namespaceID string) error
+}
+
+func recordCursor(record *Record) pagination.Cursor {
+	return pagination.Cursor{ID: record.ID}
+}
+
+func revisionCursor(revision *RecordRevision) pagination.Cursor {
+	return pagination.Cursor{ID: revision.ID}
+}
+
+// newestFirst orders revisions by ID, which is their creation order.
+var newestFirst = pagination.Order{Descending: true}
@@ -0,0 +1,180 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+)
+
+// PlanSync computes the changeset bringing the records of the namespace to
+// the desired state of the request, pinned to the versions read.
+func (s *RecordService) PlanSync(ctx context.Context, namespaceID string, request *RecordSyncRequest, actorType ActorType, actorID string) (*RecordSyncPlan, error) {
+	namespaceExists, err := s.namespaceService.Exists(ctx, namespaceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !namespaceExists {
+		return nil, fmt.Errorf("namespace not found")
+	}
+
+	records, err := s.store.List(ctx, namespaceID, 0, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if request.scope != nil {
+		records = slices.DeleteFunc(records, func(record *Record) bool {
+			return !request.scope.Matches(record.Name, string(record.Type))
+		})
+	}
+
+	var protected func(record *Record) bool
+
+	if request.ProtectOthers {
+		protected = func(record *Record) bool {
+			return record.CreatorType != actorType || record.CreatorID != actorID
+		}
+	}
+
+	diff := diffRecords(records, request.Records, protected)
+
+	return &RecordSyncPlan{
+		DryRun:    request.DryRun,
+		Changes:   diff.changes,
+		Unchanged: diff.unchanged,
+		Protected: diff.protected,
+	}, nil
+}
+
+// ApplySync validates the changes of the plan and, unless it is a dry run,
+// applies them atomically.
+func (s *RecordService) ApplySync(ctx context.Context, namespaceID string, plan *RecordSyncPlan, actorType ActorType, actorID string) (*RecordSyncPlan, error) {
+	if plan.DryRun {
+		err := s.validateChanges(ctx, namespaceID, plan.Changes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return plan, nil
+	}
+
+	if len(plan.Changes) == 0 {
+		return plan, nil
+	}
+
+	response, err := s.ApplyChanges(ctx, namespaceID, &RecordChangesRequest{Changes: plan.Changes}, actorType, actorID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Serial = response.Serial
+
+	return plan, nil
+}
+
+func (p *RecordSyncPlan) actions() []namespace.Action {
+	return (&RecordChangesRequest{Changes: p.Changes}).actions()
+}
+
+type recordDiff struct {
+	changes   []RecordChange
+	unchanged int
+	// protected holds the records that are not desired anymore but were kept.
+	protected []*Record
+}
+
+// diffRecords returns the changes turning the current records into the
+// desired ones. Records are matched by name, type and value; matched records
+// are updated in place when their TTL or class differ, so their history goes
+// on. Unmatched records are deleted unless protected.
+func diffRecords(current []*Record, desired []RecordAdditionRequest, protected func(record *Record) bool) *recordDiff {
+	key := func(name string, recordType RecordType, value string) string {
+		return fmt.Sprintf("%s %s %s", normalizeName(name), recordType, value)
+	}
+
+	wanted := make(map[string]*RecordAdditionRequest, len(desired))
+	order := make([]string, 0, len(desired))
+
+	for i := range desired {
+		k := key(desired[i].Name, desired[i].Type, desired[i].Value)
+
+		if _, ok := wanted[k]; !ok {
+			wanted[k] = &desired[i]
+			order = append(order, k)
+		}
+	}
+
+	diff := &recordDiff{
+		changes:   make([]RecordChange, 0),
+		protected: make([]*Record, 0),
+	}
+
+	matched := make(map[string]bool)
+
+	for _, record := range current {
+		k := key(record.Name, record.Type, record.Value)
+		request, ok := wanted[k]
+
+		if !ok || matched[k] {
+			if protected != nil && protected(record) {
+				diff.protected = append(diff.protected, record)
+				continue
+			}
+
+			diff.changes = append(diff.changes, RecordChange{
+				Action:   namespace.ActionDelete,
+				RecordID: record.ID.Hex(),
+				Version:  &record.Version,
+				Name:     record.Name,
+				Type:     record.Type,
+				Value:    record.Value,
+			})
+
+			continue
+		}
+
+		matched[k] = true
+
+		if record.TTL == request.TTL && record.Class == request.Class {
+			diff.unchanged++
+			continue
+		}
+
+		diff.changes = append(diff.changes, RecordChange{
+			Action:   namespace.ActionUpdate,
+			RecordID: record.ID.Hex(),
+			Version:  &record.Version,
+			Name:     record.Name,
+			Type:     record.Type,
+			Value:    record.Value,
+			TTL:      request.TTL,
+			Class:    request.Class,
+		})
+	}
+
+	for _, k := range order {
+		if matched[k] {
+			continue
+		}
+
+		request := wanted[k]
+
+		diff.changes = append(diff.changes, RecordChange{
+			Action: namespace.ActionCreate,
+			Name:   request.Name,
+			Type:   request.Type,
+			Value:  request.Value,
+			TTL:    request.TTL,
+			Class:  request.Class,
+		})
+	}
+
+	return diff
+}
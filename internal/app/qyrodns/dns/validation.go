@@ -0,0 +1,379 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ProblemCode string
+
+const (
+	ProblemMissingField  ProblemCode = "missing_field"
+	ProblemNotFound      ProblemCode = "not_found"
+	ProblemInvalidName   ProblemCode = "invalid_name"
+	ProblemInvalidType   ProblemCode = "invalid_type"
+	ProblemInvalidClass  ProblemCode = "invalid_class"
+	ProblemInvalidTTL    ProblemCode = "invalid_ttl"
+	ProblemInvalidValue  ProblemCode = "invalid_value"
+	ProblemCNAMEConflict ProblemCode = "cname_conflict"
+	ProblemTTLMismatch   ProblemCode = "ttl_mismatch"
+	ProblemOutOfZone     ProblemCode = "out_of_zone"
+)
+
+// ValidationProblem is one problem found in records.
+type ValidationProblem struct {
+	// Change is the index of the change the problem was found in, when
+	// validating a changeset.
+	Change   *int        `json:"change,omitempty"`
+	RecordID string      `json:"record_id,omitempty"`
+	Name     string      `json:"name,omitempty"`
+	Field    string      `json:"field,omitempty"`
+	Code     ProblemCode `json:"code"`
+	Message  string      `json:"message"`
+}
+
+func (p *ValidationProblem) String() string {
+	if p.Change != nil {
+		return fmt.Sprintf("change %d: %s", *p.Change, p.Message)
+	}
+
+	return p.Message
+}
+
+// ValidationError lists every problem found in records, none of which were
+// written.
+type ValidationError struct {
+	Problems []*ValidationProblem
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Problems))
+
+	for _, problem := range e.Problems {
+		messages = append(messages, problem.String())
+	}
+
+	return fmt.Sprintf("invalid records: %s", strings.Join(messages, "; "))
+}
+
+// Lint reports the problems of the records of the namespace, which may
+// predate validation.
+func (s *RecordService) Lint(ctx context.Context, namespaceID string) (*RecordLintResponse, error) {
+	namespaceExists, err := s.namespaceService.Exists(ctx, namespaceID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !namespaceExists {
+		return nil, fmt.Errorf("namespace not found")
+	}
+
+	records, err := s.store.List(ctx, namespaceID, 0, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]*ValidationProblem, 0)
+	state := make(map[string]*Record, len(records))
+
+	for _, record := range records {
+		state[record.ID.Hex()] = record
+		problems = append(problems, validateRecord(record)...)
+	}
+
+	problems = append(problems, namespaceProblems(state, nil)...)
+
+	return &RecordLintResponse{Valid: len(problems) == 0, Problems: problems}, nil
+}
+
+// validateChange validates a single change, whose problems need no index.
+func (s *RecordService) validateChange(ctx context.Context, namespaceID string, change RecordChange) error {
+	err := s.validateChanges(ctx, namespaceID, []RecordChange{change})
+
+	var validationError *ValidationError
+
+	if errors.As(err, &validationError) {
+		for _, problem := range validationError.Problems {
+			problem.Change = nil
+		}
+	}
+
+	return err
+}
+
+// validateRecord checks the fields of a record on its own.
+func validateRecord(record *Record) []*ValidationProblem {
+	problems := make([]*ValidationProblem, 0)
+
+	add := func(field string, code ProblemCode, err error) {
+		problems = append(problems, &ValidationProblem{
+			RecordID: recordID(record),
+			Name:     record.Name,
+			Field:    field,
+			Code:     code,
+			Message:  fmt.Sprintf("%s: %v", record.Name, err),
+		})
+	}
+
+	err := validateName(record.Name)
+
+	if err != nil {
+		add("name", ProblemInvalidName, err)
+	}
+
+	if record.TTL == 0 || record.TTL > math.MaxInt32 {
+		add("ttl", ProblemInvalidTTL, fmt.Errorf("ttl must be between 1 and %d", math.MaxInt32))
+	}
+
+	if record.Class != RecordClassInternet {
+		add("class", ProblemInvalidClass, fmt.Errorf("record class %s is not supported", record.Class))
+	}
+
+	_, err = ParseRecordType(string(record.Type))
+
+	if err != nil || strings.ToUpper(string(record.Type)) != string(record.Type) {
+		add("type", ProblemInvalidType, fmt.Errorf("record type %s is not supported", record.Type))
+
+		return problems
+	}
+
+	err = validateValue(record.Type, record.Value)
+
+	if err != nil {
+		add("value", ProblemInvalidValue, err)
+	}
+
+	return problems
+}
+
+// namespaceProblems checks the rules involving several records of a
+// namespace: a CNAME record cannot coexist with other records of the same
+// name, the records of an RRset must share one TTL, and once the namespace
+// has SOA records, the names of its records must be within one of their
+// zones. Only the given names are checked, or all of them when names is nil.
+func namespaceProblems(records map[string]*Record, names map[string]bool) []*ValidationProblem {
+	checked := func(name string) bool {
+		return names == nil || names[normalizeName(name)]
+	}
+
+	counts := make(map[string]int)
+	cnames := make(map[string]*Record)
+	ttls := make(map[string][]uint32)
+	zones := make([]string, 0)
+
+	for _, record := range records {
+		name := normalizeName(record.Name)
+
+		if record.Type == RecordTypeSOA {
+			zones = append(zones, name)
+		}
+
+		if !checked(name) {
+			continue
+		}
+
+		counts[name]++
+
+		rrset := name + " " + string(record.Type)
+
+		if !slices.Contains(ttls[rrset], record.TTL) {
+			ttls[rrset] = append(ttls[rrset], record.TTL)
+		}
+
+		if record.Type == RecordTypeCNAME {
+			cnames[name] = record
+		}
+	}
+
+	problems := make([]*ValidationProblem, 0)
+
+	for _, name := range sortedKeys(cnames) {
+		if counts[name] > 1 {
+			problems = append(problems, &ValidationProblem{
+				RecordID: recordID(cnames[name]),
+				Name:     name,
+				Code:     ProblemCNAMEConflict,
+				Message:  fmt.Sprintf("%s cannot have a CNAME record along with other records", name),
+			})
+		}
+	}
+
+	for _, rrset := range sortedKeys(ttls) {
+		if len(ttls[rrset]) < 2 {
+			continue
+		}
+
+		slices.Sort(ttls[rrset])
+
+		name, _, _ := strings.Cut(rrset, " ")
+
+		problems = append(problems, &ValidationProblem{
+			Name:    name,
+			Field:   "ttl",
+			Code:    ProblemTTLMismatch,
+			Message: fmt.Sprintf("%s records must share one TTL, found %v", rrset, ttls[rrset]),
+		})
+	}
+
+	if len(zones) == 0 {
+		return problems
+	}
+
+	for _, id := range sortedKeys(records) {
+		record := records[id]
+		name := normalizeName(record.Name)
+
+		if !checked(name) || inZones(name, zones) {
+			continue
+		}
+
+		problems = append(problems, &ValidationProblem{
+			RecordID: recordID(record),
+			Name:     record.Name,
+			Field:    "name",
+			Code:     ProblemOutOfZone,
+			Message:  fmt.Sprintf("%s is outside of the zones of the namespace", record.Name),
+		})
+	}
+
+	return problems
+}
+
+// validateName checks the name is a domain name of at most 253 characters,
+// made of labels of 1 to 63 characters.
+func validateName(name string) error {
+	name = strings.TrimSuffix(name, ".")
+
+	if name == "" {
+		return fmt.Errorf("name is empty")
+	}
+
+	if len(name) > 253 {
+		return fmt.Errorf("name is longer than 253 characters")
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if label == "" {
+			return fmt.Errorf("name has an empty label")
+		}
+
+		if len(label) > 63 {
+			return fmt.Errorf("label %s is longer than 63 characters", label)
+		}
+
+		if strings.ContainsAny(label, " \t\r\n") {
+			return fmt.Errorf("label %s contains whitespace", label)
+		}
+	}
+
+	return nil
+}
+
+// validateValue checks the value can be served as a record of the type.
+func validateValue(recordType RecordType, value string) error {
+	switch recordType {
+	case RecordTypeA:
+		ip := net.ParseIP(value)
+
+		if ip == nil || ip.To4() == nil || strings.Contains(value, ":") {
+			return fmt.Errorf("%s is not an IPv4 address", value)
+		}
+	case RecordTypeAAAA:
+		ip := net.ParseIP(value)
+
+		if ip == nil || !strings.Contains(value, ":") {
+			return fmt.Errorf("%s is not an IPv6 address", value)
+		}
+	case RecordTypeCNAME, RecordTypeNS:
+		err := validateName(value)
+
+		if err != nil {
+			return fmt.Errorf("target %s is invalid: %w", value, err)
+		}
+	case RecordTypeMX:
+		fields := strings.Fields(value)
+
+		if len(fields) != 2 {
+			return fmt.Errorf("%s is not a preference and an exchange", value)
+		}
+
+		_, err := strconv.ParseUint(fields[0], 10, 16)
+
+		if err != nil {
+			return fmt.Errorf("preference %s is not a number between 0 and 65535", fields[0])
+		}
+
+		err = validateName(fields[1])
+
+		if err != nil {
+			return fmt.Errorf("exchange %s is invalid: %w", fields[1], err)
+		}
+	case RecordTypeTXT:
+		if len(value) > 255 {
+			return fmt.Errorf("text is longer than 255 characters")
+		}
+	case RecordTypeSOA:
+		fields := strings.Fields(value)
+
+		if len(fields) != 7 {
+			return fmt.Errorf("%s is not a primary name server, a mailbox, a serial, a refresh, a retry, an expire and a minimum", value)
+		}
+
+		for _, name := range fields[:2] {
+			err := validateName(name)
+
+			if err != nil {
+				return fmt.Errorf("%s is invalid: %w", name, err)
+			}
+		}
+
+		for _, number := range fields[2:] {
+			_, err := strconv.ParseUint(number, 10, 32)
+
+			if err != nil {
+				return fmt.Errorf("%s is not a 32-bit number", number)
+			}
+		}
+	}
+
+	return nil
+}
+
+func inZones(name string, zones []string) bool {
+	for _, zone := range zones {
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// recordID returns the ID of the record, unless it is not created yet.
+func recordID(record *Record) string {
+	if record.ID.IsZero() {
+		return ""
+	}
+
+	return record.ID.Hex()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,7 @@
+package qyrodns
+
+// CloseDatastore closes the storage under the running server, which then
+// behaves as if the datastore were unreachable.
+func (s *Server) CloseDatastore() error {
+	return s.stores.close()
+}
@@ -1,18 +1,24 @@
 package health
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
 )
 
 type CheckHandler struct {
-	router *gin.Engine
+	router     *gin.Engine
+	staleCache *dns.StaleCache
 }
 
-func NewCheckHandler(router *gin.Engine) *CheckHandler {
+func NewCheckHandler(router *gin.Engine, staleCache *dns.StaleCache) *CheckHandler {
 	return &CheckHandler{
-		router: router,
+		router:     router,
+		staleCache: staleCache,
 	}
 }
 
@@ -23,6 +29,41 @@ func (h *CheckHandler) Register() {
 	})
 
 	h.router.GET("/health", func(c *gin.Context) {
-		c.JSONP(http.StatusOK, &CheckResponse{Status: "ok"})
+		dnsStatus := h.staleCache.Status()
+
+		status := StatusOk
+
+		if dnsStatus.Degraded {
+			status = StatusDegraded
+		}
+
+		c.JSONP(http.StatusOK, &CheckResponse{Status: status, DNS: dnsStatus})
+	})
+
+	// The metrics are in the Prometheus text format.
+	h.router.GET("/metrics", func(c *gin.Context) {
+		dnsStatus := h.staleCache.Status()
+
+		var metrics strings.Builder
+
+		degraded := 0
+
+		if dnsStatus.Degraded {
+			degraded = 1
+		}
+
+		metrics.WriteString("# HELP qyrodns_dns_degraded Whether DNS answers are served from the snapshot.\n")
+		metrics.WriteString("# TYPE qyrodns_dns_degraded gauge\n")
+		fmt.Fprintf(&metrics, "qyrodns_dns_degraded %d\n", degraded)
+
+		metrics.WriteString("# HELP qyrodns_dns_snapshot_age_seconds Age of the DNS snapshot.\n")
+		metrics.WriteString("# TYPE qyrodns_dns_snapshot_age_seconds gauge\n")
+
+		// There is no sample until a snapshot is taken.
+		if dnsStatus.SnapshotTakenAt != nil {
+			fmt.Fprintf(&metrics, "qyrodns_dns_snapshot_age_seconds %g\n", time.Since(*dnsStatus.SnapshotTakenAt).Seconds())
+		}
+
+		c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(metrics.String()))
 	})
 }
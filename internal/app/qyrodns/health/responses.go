@@ -1,7 +1,15 @@
 package health
 
+import "github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
+
+const (
+	StatusOk       = "ok"
+	StatusDegraded = "degraded"
+)
+
 type CheckResponse struct {
-	Status string `json:"status"`
+	Status string                `json:"status"`
+	DNS    *dns.StaleCacheStatus `json:"dns"`
 }
 
 type ServiceInfoResponse struct {
@@ -0,0 +1,188 @@
+package qyrodns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/migration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrations lists the schema history of the datastore. Applied migrations
+// must never be changed; add a new version instead.
+func migrations(stores *stores) []migration.Migration {
+	return []migration.Migration{
+		{
+			Version:     1,
+			Description: "create indexes",
+			Up: stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+				return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+					"admins": {
+						{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+					},
+					"api_keys": {
+						{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+						{Keys: bson.D{{Key: "secret", Value: 1}}, Options: options.Index().SetUnique(true)},
+					},
+					"namespaces": {
+						{Keys: bson.D{{Key: "name", Value: 1}}, Options: options.Index().SetUnique(true)},
+					},
+					"api_key_accesses": {
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}, {Key: "api_key_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+						{Keys: bson.D{{Key: "api_key_id", Value: 1}}},
+					},
+					"records": {
+						{Keys: bson.D{{Key: "name", Value: 1}, {Key: "type", Value: 1}}},
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}}},
+					},
+				})
+			}),
+		},
+		{
+			Version:     2,
+			Description: "index namespace status",
+			Up: stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+				return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+					"namespaces": {
+						{Keys: bson.D{{Key: "status", Value: 1}}},
+					},
+				})
+			}),
+		},
+		{
+			Version:     3,
+			Description: "index record deletion time",
+			Up: stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+				return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+					"records": {
+						{Keys: bson.D{{Key: "deleted_at", Value: 1}}},
+					},
+				})
+			}),
+		},
+		{
+			Version:     4,
+			Description: "index record revisions",
+			Up: stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+				return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+					"record_revisions": {
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}, {Key: "record_id", Value: 1}, {Key: "_id", Value: -1}}},
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}, {Key: "created_at", Value: 1}}},
+					},
+				})
+			}),
+		},
+		{
+			Version:     5,
+			Description: "index record listing filters",
+			Up: stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+				return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+					"records": {
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}, {Key: "name", Value: 1}}},
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}, {Key: "updated_at", Value: 1}}},
+						{Keys: bson.D{{Key: "namespace_id", Value: 1}, {Key: "creator_id", Value: 1}}},
+					},
+				})
+			}),
+		},
+		{
+			Version:     6,
+			Description: "index record values",
+			Up: stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+				return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+					"records": {
+						{Keys: bson.D{{Key: "value", Value: 1}}},
+					},
+				})
+			}),
+		},
+		{
+			// Secrets were stored in plaintext and looked up by a unique index,
+			// which must go before they are unset.
+			Version:     7,
+			Description: "hash api key secrets",
+			Up: func(ctx context.Context) error {
+				err := stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+					return dropIndex(ctx, database, "api_keys", "secret_1")
+				})(ctx)
+
+				if err != nil {
+					return err
+				}
+
+				_, err = stores.apiKeys.HashLegacySecrets(ctx)
+
+				if err != nil {
+					return err
+				}
+
+				return stores.onMongo(func(ctx context.Context, database *mongo.Database) error {
+					return createIndexes(ctx, database, map[string][]mongo.IndexModel{
+						"api_keys": {
+							{
+								Keys:    bson.D{{Key: "legacy_secret_hash", Value: 1}},
+								Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"legacy_secret_hash": bson.M{"$exists": true}}),
+							},
+						},
+					})
+				})(ctx)
+			},
+		},
+		{
+			// Admins created before roles could do everything.
+			Version:     8,
+			Description: "make superadmins of admins without roles",
+			Up: func(ctx context.Context) error {
+				_, err := stores.admins.GrantSuperadminToRoleless(ctx)
+
+				return err
+			},
+		},
+	}
+}
+
+// onMongo runs fn only on the MongoDB backend. The embedded backends scan
+// their collections and enforce uniqueness in transactions, so they have no
+// indexes to maintain.
+func (s *stores) onMongo(fn func(ctx context.Context, database *mongo.Database) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if s.mongoDatabase == nil {
+			return nil
+		}
+
+		return fn(ctx, s.mongoDatabase)
+	}
+}
+
+// dropIndex drops the index unless it is already gone, as when another
+// instance applied the migration first.
+func dropIndex(ctx context.Context, database *mongo.Database, collection string, name string) error {
+	_, err := database.Collection(collection).Indexes().DropOne(ctx, name)
+
+	var commandError mongo.CommandError
+
+	if errors.As(err, &commandError) && (commandError.Name == "IndexNotFound" || commandError.Name == "NamespaceNotFound") {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("error dropping index %s on %s: %w", name, collection, err)
+	}
+
+	return nil
+}
+
+func createIndexes(ctx context.Context, database *mongo.Database, indexes map[string][]mongo.IndexModel) error {
+	for collection, models := range indexes {
+		_, err := database.Collection(collection).Indexes().CreateMany(ctx, models)
+
+		if err != nil {
+			return fmt.Errorf("error creating indexes on %s: %w", collection, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package namespace
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ApiKeyAccessEmbeddedStore struct {
+	accesses *embedded.Collection[ApiKeyAccess]
+}
+
+func NewApiKeyAccessEmbeddedStore(db embedded.DB) *ApiKeyAccessEmbeddedStore {
+	return &ApiKeyAccessEmbeddedStore{accesses: embedded.NewCollection[ApiKeyAccess](db, "api_key_accesses")}
+}
+
+func (s *ApiKeyAccessEmbeddedStore) AddActions(ctx context.Context, namespaceID string, apiKeyID string, actions []Action, scope *Scope, creatorID string) error {
+	return s.accesses.WithTransaction(ctx, func(ctx context.Context) error {
+		access, err := s.find(ctx, namespaceID, apiKeyID)
+
+		if err != nil {
+			return err
+		}
+
+		if access == nil {
+			access = &ApiKeyAccess{
+				ID:          primitive.NewObjectID(),
+				NamespaceID: namespaceID,
+				ApiKeyID:    apiKeyID,
+				Actions:     make([]string, 0),
+				CreatorID:   creatorID,
+				CreatedAt:   time.Now(),
+			}
+		}
+
+		for _, action := range actions {
+			if !slices.Contains(access.Actions, string(action)) {
+				access.Actions = append(access.Actions, string(action))
+			}
+		}
+
+		if scope != nil {
+			access.Scope = *scope
+		}
+
+		access.UpdatedAt = time.Now()
+
+		return s.accesses.Put(ctx, access.ID.Hex(), access)
+	})
+}
+
+func (s *ApiKeyAccessEmbeddedStore) RemoveActions(ctx context.Context, namespaceID string, apiKeyID string, actions []Action) error {
+	return s.accesses.WithTransaction(ctx, func(ctx context.Context) error {
+		access, err := s.find(ctx, namespaceID, apiKeyID)
+
+		if err != nil {
+			return err
+		}
+
+		if access == nil {
+			return storage.ErrNotFound
+		}
+
+		access.Actions = slices.DeleteFunc(access.Actions, func(action string) bool {
+			return slices.Contains(actions, Action(action))
+		})
+		access.UpdatedAt = time.Now()
+
+		return s.accesses.Put(ctx, access.ID.Hex(), access)
+	})
+}
+
+func (s *ApiKeyAccessEmbeddedStore) List(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[ApiKeyAccess], error) {
+	accesses, err := s.accesses.Find(ctx, func(access *ApiKeyAccess) bool {
+		return access.NamespaceID == namespaceID
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(accesses, request, pagination.Order{}, accessCursor), nil
+}
+
+func (s *ApiKeyAccessEmbeddedStore) Delete(ctx context.Context, namespaceID string, apiKeyID string) error {
+	deleted, err := s.accesses.DeleteWhere(ctx, func(access *ApiKeyAccess) bool {
+		return access.NamespaceID == namespaceID && access.ApiKeyID == apiKeyID
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ApiKeyAccessEmbeddedStore) Get(ctx context.Context, namespaceID string, apiKeyID string) (*ApiKeyAccess, error) {
+	access, err := s.find(ctx, namespaceID, apiKeyID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if access == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	return access, nil
+}
+
+func (s *ApiKeyAccessEmbeddedStore) ListByApiKeyID(ctx context.Context, apiKeyID string) ([]*ApiKeyAccess, error) {
+	return s.accesses.Find(ctx, func(access *ApiKeyAccess) bool {
+		return access.ApiKeyID == apiKeyID
+	})
+}
+
+func (s *ApiKeyAccessEmbeddedStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	_, err := s.accesses.DeleteWhere(ctx, func(access *ApiKeyAccess) bool {
+		return access.NamespaceID == namespaceID
+	})
+
+	return err
+}
+
+func (s *ApiKeyAccessEmbeddedStore) find(ctx context.Context, namespaceID string, apiKeyID string) (*ApiKeyAccess, error) {
+	accesses, err := s.accesses.Find(ctx, func(access *ApiKeyAccess) bool {
+		return access.NamespaceID == namespaceID && access.ApiKeyID == apiKeyID
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accesses) == 0 {
+		return nil, nil
+	}
+
+	return accesses[0], nil
+}
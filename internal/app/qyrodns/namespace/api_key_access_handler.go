@@ -3,21 +3,21 @@ package namespace
 import (
 	"context"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
 type ApiKeyAccessHandler struct {
-	router        *gin.Engine
-	authenticator *auth.Authenticator
-	service       *ApiKeyAccessService
+	router     *gin.Engine
+	authorizer *admin.Authorizer
+	service    *ApiKeyAccessService
 }
 
-func NewApiKeyAccessHandler(router *gin.Engine, authenticator *auth.Authenticator, service *ApiKeyAccessService) *ApiKeyAccessHandler {
-	return &ApiKeyAccessHandler{router: router, authenticator: authenticator, service: service}
+func NewApiKeyAccessHandler(router *gin.Engine, authorizer *admin.Authorizer, service *ApiKeyAccessService) *ApiKeyAccessHandler {
+	return &ApiKeyAccessHandler{router: router, authorizer: authorizer, service: service}
 }
 
 func (h *ApiKeyAccessHandler) Register() {
@@ -26,13 +26,9 @@ func (h *ApiKeyAccessHandler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		if !ok {
 			return
 		}
 
@@ -49,7 +45,7 @@ func (h *ApiKeyAccessHandler) Register() {
 			return
 		}
 
-		err = h.service.Add(ctx, namespaceID, &req, aa.ID)
+		err := h.service.Add(ctx, namespaceID, &req, aa.ID.Hex())
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -70,14 +66,9 @@ func (h *ApiKeyAccessHandler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
 
+		if !ok {
 			return
 		}
 
@@ -94,7 +85,7 @@ func (h *ApiKeyAccessHandler) Register() {
 			return
 		}
 
-		err = h.service.Delete(ctx, namespaceID, &req)
+		err := h.service.Delete(ctx, namespaceID, &req)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -115,14 +106,9 @@ func (h *ApiKeyAccessHandler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
 
+		if !ok {
 			return
 		}
 
@@ -139,7 +125,7 @@ func (h *ApiKeyAccessHandler) Register() {
 			return
 		}
 
-		err = h.service.Destroy(ctx, namespaceID, &req)
+		err := h.service.Destroy(ctx, namespaceID, &req)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -160,32 +146,15 @@ func (h *ApiKeyAccessHandler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
 
+		if !ok {
 			return
 		}
 
 		namespaceID := c.Param("namespaceID")
 
-		page := c.DefaultQuery("page", "0")
-		size := c.DefaultQuery("size", "50")
-		pageInt, err := strconv.ParseInt(page, 10, 64)
-
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
-
-			return
-		}
-
-		sizeInt, err := strconv.ParseInt(size, 10, 64)
+		request, err := pagination.FromQuery(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -196,7 +165,7 @@ func (h *ApiKeyAccessHandler) Register() {
 			return
 		}
 
-		apiKeyAccesses, err := h.service.List(ctx, namespaceID, pageInt, sizeInt)
+		apiKeyAccesses, err := h.service.List(ctx, namespaceID, request)
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -0,0 +1,151 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ApiKeyAccessMongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewApiKeyAccessMongoStore(mongo *mongo.Collection) *ApiKeyAccessMongoStore {
+	return &ApiKeyAccessMongoStore{mongo: mongo}
+}
+
+func (s *ApiKeyAccessMongoStore) AddActions(ctx context.Context, namespaceID string, apiKeyID string, actions []Action, scope *Scope, creatorID string) error {
+	fields := bson.M{
+		"updated_at": time.Now(),
+	}
+
+	if scope != nil {
+		fields["scope"] = scope
+	}
+
+	_, err := s.mongo.UpdateOne(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"api_key_id":   apiKeyID,
+	}, bson.M{
+		"$set": fields,
+		"$setOnInsert": bson.M{
+			"namespace_id": namespaceID,
+			"api_key_id":   apiKeyID,
+			"creator_id":   creatorID,
+			"created_at":   time.Now(),
+		},
+		"$addToSet": bson.M{
+			"actions": bson.M{
+				"$each": actions,
+			},
+		},
+	}, options.Update().SetUpsert(true))
+
+	return err
+}
+
+func (s *ApiKeyAccessMongoStore) RemoveActions(ctx context.Context, namespaceID string, apiKeyID string, actions []Action) error {
+	result, err := s.mongo.UpdateOne(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"api_key_id":   apiKeyID,
+	}, bson.M{
+		"$set": bson.M{
+			"updated_at": time.Now(),
+		},
+		"$pullAll": bson.M{
+			"actions": actions,
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ApiKeyAccessMongoStore) List(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[ApiKeyAccess], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"namespace_id": namespaceID,
+	}, request, pagination.Order{}, accessCursor)
+}
+
+func (s *ApiKeyAccessMongoStore) Delete(ctx context.Context, namespaceID string, apiKeyID string) error {
+	result, err := s.mongo.DeleteOne(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"api_key_id":   apiKeyID,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return storage.ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ApiKeyAccessMongoStore) Get(ctx context.Context, namespaceID string, apiKeyID string) (*ApiKeyAccess, error) {
+	result := s.mongo.FindOne(ctx, bson.M{
+		"namespace_id": namespaceID,
+		"api_key_id":   apiKeyID,
+	})
+
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, storage.ErrNotFound
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	access := &ApiKeyAccess{}
+
+	err := result.Decode(access)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return access, nil
+}
+
+func (s *ApiKeyAccessMongoStore) ListByApiKeyID(ctx context.Context, apiKeyID string) ([]*ApiKeyAccess, error) {
+	result, err := s.mongo.Find(ctx, bson.M{
+		"api_key_id": apiKeyID,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	accesses := make([]*ApiKeyAccess, 0)
+
+	err = result.All(ctx, &accesses)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return accesses, nil
+}
+
+func (s *ApiKeyAccessMongoStore) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	_, err := s.mongo.DeleteMany(ctx, bson.M{
+		"namespace_id": namespaceID,
+	})
+
+	return err
+}
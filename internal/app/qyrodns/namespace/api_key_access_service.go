@@ -2,24 +2,23 @@ package namespace
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
 
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/apikey"
 	apiKeyCore "github.com/qyrocloud/qyrodns/internal/pkg/apikey"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
 )
 
 type ApiKeyAccessService struct {
-	mongo         *mongo.Collection
+	store         ApiKeyAccessStore
 	service       *Service
 	apiKeyService *apikey.Service
 }
 
-func NewApiKeyAccessService(mongo *mongo.Collection, service *Service, apiKeyService *apikey.Service) *ApiKeyAccessService {
-	return &ApiKeyAccessService{mongo: mongo, service: service, apiKeyService: apiKeyService}
+func NewApiKeyAccessService(store ApiKeyAccessStore, service *Service, apiKeyService *apikey.Service) *ApiKeyAccessService {
+	return &ApiKeyAccessService{store: store, service: service, apiKeyService: apiKeyService}
 }
 
 func (s *ApiKeyAccessService) Add(ctx context.Context, namespaceID string, request *ApiKeyAccessRequest, creatorID string) error {
@@ -43,70 +42,41 @@ func (s *ApiKeyAccessService) Add(ctx context.Context, namespaceID string, reque
 		return fmt.Errorf("api key not found")
 	}
 
-	_, err = s.mongo.UpdateOne(ctx, bson.M{
-		"namespace_id": namespaceID,
-		"api_key_id":   request.ApiKeyID,
-	}, bson.M{
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
-		"$setOnInsert": bson.M{
-			"namespace_id": namespaceID,
-			"api_key_id":   request.ApiKeyID,
-			"creator_id":   creatorID,
-			"created_at":   time.Now(),
-		},
-		"$addToSet": bson.M{
-			"actions": bson.M{
-				"$each": request.Actions,
-			},
-		},
-	}, options.Update().SetUpsert(true))
+	if request.Scope != nil {
+		err = request.Scope.normalize()
 
-	return err
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.store.AddActions(ctx, namespaceID, request.ApiKeyID, request.Actions, request.Scope, creatorID)
 }
 
 func (s *ApiKeyAccessService) Delete(ctx context.Context, namespaceID string, request *ApiKeyAccessRequest) error {
-	result, err := s.mongo.UpdateOne(ctx, bson.M{
-		"namespace_id": namespaceID,
-		"api_key_id":   request.ApiKeyID,
-	}, bson.M{
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
-		"$pullAll": bson.M{
-			"actions": request.Actions,
-		},
-	})
-
-	if err != nil {
-		return err
-	}
+	err := s.store.RemoveActions(ctx, namespaceID, request.ApiKeyID, request.Actions)
 
-	if result.MatchedCount == 0 {
+	if errors.Is(err, storage.ErrNotFound) {
 		return fmt.Errorf("api key access to namespace does not exist")
 	}
 
-	return nil
+	return err
 }
 
-func (s *ApiKeyAccessService) List(ctx context.Context, namespaceID string, page int64, size int64) ([]*ApiKeyAccessResponse, error) {
-	result, err := s.mongo.Find(ctx, bson.M{
-		"namespace_id": namespaceID,
-	}, options.Find().SetSkip(page*size).SetLimit(size))
+func (s *ApiKeyAccessService) List(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[ApiKeyAccessResponse], error) {
+	page, err := s.store.List(ctx, namespaceID, request)
 
 	if err != nil {
 		return nil, err
 	}
 
-	accesses := make([]*ApiKeyAccess, 0)
-
-	err = result.All(ctx, &accesses)
-
-	if err != nil {
-		return nil, err
-	}
+	return pagination.Map(page, func(accesses []*ApiKeyAccess) ([]*ApiKeyAccessResponse, error) {
+		return s.withApiKeys(ctx, accesses)
+	})
+}
 
+// withApiKeys returns the accesses along with their API keys.
+func (s *ApiKeyAccessService) withApiKeys(ctx context.Context, accesses []*ApiKeyAccess) ([]*ApiKeyAccessResponse, error) {
 	if len(accesses) == 0 {
 		return make([]*ApiKeyAccessResponse, 0), nil
 	}
@@ -142,40 +112,97 @@ func (s *ApiKeyAccessService) List(ctx context.Context, namespaceID string, page
 }
 
 func (s *ApiKeyAccessService) Destroy(ctx context.Context, namespaceID string, request *ApiKeyAccessDestroyRequest) error {
-	result, err := s.mongo.DeleteOne(ctx, bson.M{
-		"namespace_id": namespaceID,
-		"api_key_id":   request.ApiKeyID,
-	})
+	err := s.store.Delete(ctx, namespaceID, request.ApiKeyID)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("api key access to namespace does not exist")
+	}
+
+	return err
+}
+
+// Get returns the access of the API key to the namespace, or nil when it has
+// none.
+func (s *ApiKeyAccessService) Get(ctx context.Context, namespaceID string, apiKeyID string) (*ApiKeyAccess, error) {
+	access, err := s.store.Get(ctx, namespaceID, apiKeyID)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("api key access to namespace does not exist")
+	err = access.Scope.compile()
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return access, nil
 }
 
-func (s *ApiKeyAccessService) HasPermission(ctx context.Context, namespaceID string, apiKeyID string, action Action) (bool, error) {
-	count, err := s.mongo.CountDocuments(ctx, bson.M{
-		"namespace_id": namespaceID,
-		"api_key_id":   apiKeyID,
-		"actions":      action,
-	})
+// Self returns the API key along with its accesses to active namespaces.
+func (s *ApiKeyAccessService) Self(ctx context.Context, apiKeyID string) (*SelfResponse, error) {
+	apiKey, err := s.apiKeyService.Get(ctx, apiKeyID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	accesses, err := s.store.ListByApiKeyID(ctx, apiKeyID)
 
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return count > 0, nil
+	namespaces := make([]*NamespaceAccess, 0, len(accesses))
+
+	for _, access := range accesses {
+		if len(access.Actions) == 0 {
+			continue
+		}
+
+		namespace, err := s.service.Get(ctx, access.NamespaceID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !namespace.Active() {
+			continue
+		}
+
+		namespaces = append(namespaces, &NamespaceAccess{
+			Namespace: namespace,
+			Actions:   access.Actions,
+			Scope:     access.Scope,
+		})
+	}
+
+	return &SelfResponse{ApiKey: apiKey, Namespaces: namespaces}, nil
 }
 
-func (s *ApiKeyAccessService) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
-	_, err := s.mongo.DeleteMany(ctx, bson.M{
-		"namespace_id": namespaceID,
-	})
+// ListReadable lists the active namespaces the API key can read records of.
+func (s *ApiKeyAccessService) ListReadable(ctx context.Context, apiKeyID string, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	accesses, err := s.store.ListByApiKeyID(ctx, apiKeyID)
 
-	return err
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceIDs := make([]string, 0, len(accesses))
+
+	for _, access := range accesses {
+		if access.Allows(ActionRead) {
+			namespaceIDs = append(namespaceIDs, access.NamespaceID)
+		}
+	}
+
+	return s.service.ListByIDs(ctx, namespaceIDs, request)
+}
+
+func (s *ApiKeyAccessService) DeleteByNamespaceID(ctx context.Context, namespaceID string) error {
+	return s.store.DeleteByNamespaceID(ctx, namespaceID)
 }
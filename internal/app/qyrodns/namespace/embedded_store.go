@@ -0,0 +1,218 @@
+package namespace
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EmbeddedStore struct {
+	namespaces *embedded.Collection[Namespace]
+}
+
+func NewEmbeddedStore(db embedded.DB) *EmbeddedStore {
+	return &EmbeddedStore{namespaces: embedded.NewCollection[Namespace](db, "namespaces")}
+}
+
+func (s *EmbeddedStore) Insert(ctx context.Context, namespace *Namespace) error {
+	return s.namespaces.WithTransaction(ctx, func(ctx context.Context) error {
+		nameTaken, err := s.nameTaken(ctx, namespace.Name, namespace.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if nameTaken {
+			return storage.ErrDuplicate
+		}
+
+		return s.namespaces.Put(ctx, namespace.ID.Hex(), namespace)
+	})
+}
+
+func (s *EmbeddedStore) List(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	namespaces, err := s.namespaces.Find(ctx, func(namespace *Namespace) bool {
+		return namespace.Active()
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(namespaces, request, pagination.Order{}, cursor), nil
+}
+
+func (s *EmbeddedStore) ListTrash(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	namespaces, err := s.ListByStatus(ctx, StatusDeleted)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(namespaces, request, pagination.Order{}, cursor), nil
+}
+
+func (s *EmbeddedStore) ListByIDs(ctx context.Context, ids []primitive.ObjectID, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	namespaces, err := s.namespaces.Find(ctx, func(namespace *Namespace) bool {
+		return namespace.Active() && slices.Contains(ids, namespace.ID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pagination.FromSlice(namespaces, request, pagination.Order{}, cursor), nil
+}
+
+func (s *EmbeddedStore) Get(ctx context.Context, id primitive.ObjectID) (*Namespace, error) {
+	return s.namespaces.Get(ctx, id.Hex())
+}
+
+func (s *EmbeddedStore) Update(ctx context.Context, id primitive.ObjectID, request *UpdateRequest, version *int64) (*Namespace, error) {
+	var namespace *Namespace
+
+	err := s.namespaces.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		namespace, err = s.namespaces.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		if version != nil && namespace.Version != *version {
+			return storage.ErrConflict
+		}
+
+		namespace.Version++
+		namespace.UpdatedAt = time.Now()
+
+		if request.Name != "" {
+			nameTaken, err := s.nameTaken(ctx, request.Name, id)
+
+			if err != nil {
+				return err
+			}
+
+			if nameTaken {
+				return storage.ErrDuplicate
+			}
+
+			namespace.Name = request.Name
+		}
+
+		return s.namespaces.Put(ctx, id.Hex(), namespace)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespace, nil
+}
+
+func (s *EmbeddedStore) SetStatus(ctx context.Context, id primitive.ObjectID, from Status, version int64, status Status, deletedAt *time.Time) (*Namespace, error) {
+	var namespace *Namespace
+
+	err := s.namespaces.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		namespace, err = s.namespaces.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		current := namespace.Status
+
+		if namespace.Active() {
+			current = StatusActive
+		}
+
+		if current != from || namespace.Version != version {
+			return storage.ErrConflict
+		}
+
+		namespace.Status = status
+		namespace.DeletedAt = deletedAt
+		namespace.Version++
+		namespace.UpdatedAt = time.Now()
+
+		return s.namespaces.Put(ctx, id.Hex(), namespace)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespace, nil
+}
+
+func (s *EmbeddedStore) ListByStatus(ctx context.Context, status Status) ([]*Namespace, error) {
+	return s.namespaces.Find(ctx, func(namespace *Namespace) bool {
+		return namespace.Status == status
+	})
+}
+
+func (s *EmbeddedStore) BumpSerial(ctx context.Context, id primitive.ObjectID, floor uint32) (*Namespace, error) {
+	var namespace *Namespace
+
+	err := s.namespaces.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		namespace, err = s.namespaces.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		namespace.Serial = max(namespace.Serial, floor) + 1
+
+		return s.namespaces.Put(ctx, id.Hex(), namespace)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespace, nil
+}
+
+func (s *EmbeddedStore) Delete(ctx context.Context, id primitive.ObjectID) (*Namespace, error) {
+	var namespace *Namespace
+
+	err := s.namespaces.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+
+		namespace, err = s.namespaces.Get(ctx, id.Hex())
+
+		if err != nil {
+			return err
+		}
+
+		return s.namespaces.Delete(ctx, id.Hex())
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespace, nil
+}
+
+func (s *EmbeddedStore) nameTaken(ctx context.Context, name string, excludedID primitive.ObjectID) (bool, error) {
+	count, err := s.namespaces.Count(ctx, func(namespace *Namespace) bool {
+		return namespace.Name == name && namespace.ID != excludedID
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
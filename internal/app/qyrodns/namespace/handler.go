@@ -3,21 +3,22 @@ package namespace
 import (
 	"context"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/etag"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
 )
 
 type Handler struct {
-	router        *gin.Engine
-	authenticator *auth.Authenticator
-	service       *Service
+	router     *gin.Engine
+	authorizer *admin.Authorizer
+	service    *Service
 }
 
-func NewHandler(router *gin.Engine, authenticator *auth.Authenticator, service *Service) *Handler {
-	return &Handler{router: router, authenticator: authenticator, service: service}
+func NewHandler(router *gin.Engine, authorizer *admin.Authorizer, service *Service) *Handler {
+	return &Handler{router: router, authorizer: authorizer, service: service}
 }
 
 func (h *Handler) Register() {
@@ -26,13 +27,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		aa, err := h.authenticator.ValidateAdminContext(c)
+		aa, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, "")
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		if !ok {
 			return
 		}
 
@@ -47,7 +44,7 @@ func (h *Handler) Register() {
 			return
 		}
 
-		namespace, err := h.service.Create(ctx, &req, aa.ID)
+		namespace, err := h.service.Create(ctx, &req, aa.ID.Hex())
 
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -58,6 +55,7 @@ func (h *Handler) Register() {
 			return
 		}
 
+		etag.Set(c, namespace.Version)
 		c.JSON(http.StatusCreated, namespace)
 	})
 
@@ -65,19 +63,13 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		aa, ok := h.authorizer.Authenticate(c, ctx)
 
+		if !ok {
 			return
 		}
 
-		page := c.DefaultQuery("page", "0")
-		size := c.DefaultQuery("size", "50")
-		pageInt, err := strconv.ParseInt(page, 10, 64)
+		request, err := pagination.FromQuery(c)
 
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -88,19 +80,15 @@ func (h *Handler) Register() {
 			return
 		}
 
-		sizeInt, err := strconv.ParseInt(size, 10, 64)
-
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		// Admins with roles limited to some namespaces only see those.
+		var namespaces *pagination.Page[Namespace]
 
-			return
+		if aa.Allows(admin.PermissionRead, "") {
+			namespaces, err = h.service.List(ctx, request)
+		} else {
+			namespaces, err = h.service.ListByIDs(ctx, aa.NamespaceIDs(admin.PermissionRead), request)
 		}
 
-		namespaces, err := h.service.List(ctx, pageInt, sizeInt)
-
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -117,14 +105,9 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
-
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": err.Error(),
-			})
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionRead, c.Param("namespaceID"))
 
+		if !ok {
 			return
 		}
 
@@ -141,6 +124,7 @@ func (h *Handler) Register() {
 			return
 		}
 
+		etag.Set(c, namespace.Version)
 		c.JSON(http.StatusOK, namespace)
 	})
 
@@ -148,10 +132,17 @@ func (h *Handler) Register() {
 		ctx, cancel := context.WithTimeout(c, time.Second*5)
 		defer cancel()
 
-		_, err := h.authenticator.ValidateAdminContext(c)
+		_, ok := h.authorizer.Authorize(c, ctx, admin.PermissionWrite, c.Param("namespaceID"))
 
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
+		if !ok {
+			return
+		}
+
+		namespaceID := c.Param("namespaceID")
+
+		var req UpdateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -159,10 +150,9 @@ func (h *Handler) Register() {
 			return
 		}
 
-		namespaceID := c.Param("namespaceID")
+		ifMatch, err := etag.IfMatch(c)
 
-		var req UpdateRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"message": err.Error(),
@@ -171,10 +161,10 @@ func (h *Handler) Register() {
 			return
 		}
 
-		namespace, err := h.service.Update(ctx, namespaceID, &req)
+		namespace, err := h.service.Update(ctx, namespaceID, &req, ifMatch)
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(etag.Status(err), gin.H{
 				"success": false,
 				"message": err.Error(),
 			})
@@ -182,6 +172,7 @@ func (h *Handler) Register() {
 			return
 		}
 
+		etag.Set(c, namespace.Version)
 		c.JSON(http.StatusOK, namespace)
 	})
 }
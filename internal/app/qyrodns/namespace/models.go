@@ -1,29 +1,63 @@
 package namespace
 
 import (
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Namespace struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id"`
-	Name      string             `json:"name" bson:"name"`
-	CreatorID string             `json:"creator_id" bson:"creator_id"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID     primitive.ObjectID `json:"id" bson:"_id"`
+	Name   string             `json:"name" bson:"name"`
+	Status Status             `json:"status" bson:"status"`
+	// Serial is bumped once for every change of the namespace's records and
+	// served as the serial of its SOA records.
+	Serial uint32 `json:"serial" bson:"serial"`
+	// Version is incremented on every change of the namespace itself and
+	// served as its ETag.
+	Version   int64      `json:"version" bson:"version"`
+	CreatorID string     `json:"creator_id" bson:"creator_id"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" bson:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
 
+// Active reports whether the namespace serves its records.
+func (n *Namespace) Active() bool {
+	return n.Status == StatusActive || n.Status == ""
+}
+
+// Status is the lifecycle state of a namespace. Namespaces created before
+// statuses existed have an empty status and are active.
+type Status string
+
+const (
+	StatusActive Status = "active"
+	// StatusDeleted namespaces are in the trash: they no longer serve records
+	// and can be restored until the retention period expires.
+	StatusDeleted Status = "deleted"
+	// StatusDeleting namespaces no longer serve records; their API key
+	// accesses and records are being removed before the namespace itself.
+	StatusDeleting Status = "deleting"
+)
+
 type ApiKeyAccess struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id"`
 	NamespaceID string             `json:"namespace_id" bson:"namespace_id"`
 	ApiKeyID    string             `json:"api_key_id" bson:"api_key_id"`
 	Actions     []string           `json:"actions" bson:"actions"`
+	Scope       Scope              `json:"scope" bson:"scope"`
 	CreatorID   string             `json:"creator_id" bson:"creator_id"`
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// Allows reports whether the access grants the action.
+func (a *ApiKeyAccess) Allows(action Action) bool {
+	return slices.Contains(a.Actions, string(action))
+}
+
 type Action string
 
 const (
@@ -0,0 +1,200 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewMongoStore(mongo *mongo.Collection) *MongoStore {
+	return &MongoStore{mongo: mongo}
+}
+
+func (s *MongoStore) Insert(ctx context.Context, namespace *Namespace) error {
+	_, err := s.mongo.InsertOne(ctx, namespace)
+
+	if mongo.IsDuplicateKeyError(err) {
+		return storage.ErrDuplicate
+	}
+
+	return err
+}
+
+func (s *MongoStore) List(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"status": bson.M{"$nin": []Status{StatusDeleted, StatusDeleting}},
+	}, request, pagination.Order{}, cursor)
+}
+
+func (s *MongoStore) ListTrash(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"status": StatusDeleted,
+	}, request, pagination.Order{}, cursor)
+}
+
+func (s *MongoStore) ListByIDs(ctx context.Context, ids []primitive.ObjectID, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	return pagination.Find(ctx, s.mongo, bson.M{
+		"_id":    bson.M{"$in": ids},
+		"status": bson.M{"$nin": []Status{StatusDeleted, StatusDeleting}},
+	}, request, pagination.Order{}, cursor)
+}
+
+func (s *MongoStore) Get(ctx context.Context, id primitive.ObjectID) (*Namespace, error) {
+	result := s.mongo.FindOne(ctx, bson.M{"_id": id})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) Update(ctx context.Context, id primitive.ObjectID, request *UpdateRequest, version *int64) (*Namespace, error) {
+	fields := bson.M{
+		"updated_at": time.Now(),
+	}
+
+	if request.Name != "" {
+		fields["name"] = request.Name
+	}
+
+	filter := bson.M{
+		"_id": id,
+	}
+
+	if version != nil {
+		filter["version"] = storage.VersionFilter(*version)
+	}
+
+	result := s.mongo.FindOneAndUpdate(ctx, filter, bson.M{
+		"$set": fields,
+		"$inc": bson.M{"version": 1},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	namespace, err := s.decode(result)
+
+	if version == nil || !errors.Is(err, storage.ErrNotFound) {
+		return namespace, err
+	}
+
+	_, err = s.Get(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, storage.ErrConflict
+}
+
+func (s *MongoStore) SetStatus(ctx context.Context, id primitive.ObjectID, from Status, version int64, status Status, deletedAt *time.Time) (*Namespace, error) {
+	update := bson.M{
+		"$set": bson.M{
+			"status":     status,
+			"updated_at": time.Now(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	if deletedAt != nil {
+		update["$set"].(bson.M)["deleted_at"] = deletedAt
+	} else {
+		update["$unset"] = bson.M{"deleted_at": ""}
+	}
+
+	statusFilter := any(from)
+
+	if from == StatusActive {
+		// Namespaces created before statuses existed have none.
+		statusFilter = bson.M{"$in": bson.A{StatusActive, "", nil}}
+	}
+
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
+		"_id":     id,
+		"status":  statusFilter,
+		"version": storage.VersionFilter(version),
+	}, update, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	namespace, err := s.decode(result)
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return namespace, err
+	}
+
+	_, err = s.Get(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, storage.ErrConflict
+}
+
+func (s *MongoStore) ListByStatus(ctx context.Context, status Status) ([]*Namespace, error) {
+	result, err := s.mongo.Find(ctx, bson.M{"status": status})
+
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]*Namespace, 0)
+
+	err = result.All(ctx, &namespaces)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces, nil
+}
+
+func (s *MongoStore) BumpSerial(ctx context.Context, id primitive.ObjectID, floor uint32) (*Namespace, error) {
+	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
+		"_id": id,
+	}, bson.A{
+		bson.M{"$set": bson.M{"serial": bson.M{"$add": bson.A{
+			bson.M{"$max": bson.A{bson.M{"$ifNull": bson.A{"$serial", 0}}, int64(floor)}},
+			1,
+		}}}},
+	}, options.FindOneAndUpdate().SetReturnDocument(options.After))
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) Delete(ctx context.Context, id primitive.ObjectID) (*Namespace, error) {
+	result := s.mongo.FindOneAndDelete(ctx, bson.M{
+		"_id": id,
+	})
+
+	return s.decode(result)
+}
+
+func (s *MongoStore) decode(result *mongo.SingleResult) (*Namespace, error) {
+	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+		return nil, storage.ErrNotFound
+	}
+
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		return nil, storage.ErrDuplicate
+	}
+
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+
+	namespace := &Namespace{}
+
+	err := result.Decode(namespace)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return namespace, nil
+}
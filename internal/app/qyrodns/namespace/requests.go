@@ -11,6 +11,8 @@ type UpdateRequest struct {
 type ApiKeyAccessRequest struct {
 	ApiKeyID string   `json:"api_key_id" binding:"required"`
 	Actions  []Action `json:"actions" binding:"required"`
+	// Scope replaces the scope of the access when granting, if set.
+	Scope *Scope `json:"scope"`
 }
 
 type ApiKeyAccessDestroyRequest struct {
@@ -4,6 +4,19 @@ import (
 	"github.com/qyrocloud/qyrodns/internal/pkg/apikey"
 )
 
+// SelfResponse tells an API key what it may do.
+type SelfResponse struct {
+	ApiKey     *apikey.ApiKey     `json:"api_key"`
+	Namespaces []*NamespaceAccess `json:"namespaces"`
+}
+
+// NamespaceAccess is an access of an API key along with its namespace.
+type NamespaceAccess struct {
+	Namespace *Namespace `json:"namespace"`
+	Actions   []string   `json:"actions"`
+	Scope     Scope      `json:"scope"`
+}
+
 type ApiKeyAccessResponse struct {
 	Access *ApiKeyAccess  `json:"access"`
 	ApiKey *apikey.ApiKey `json:"api_key"`
@@ -0,0 +1,106 @@
+package namespace
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Scope restricts the records an API key access applies to. Empty lists match
+// every record.
+type Scope struct {
+	// NamePatterns are globs matched case-insensitively against record names,
+	// regardless of a trailing dot. * matches any characters, dots included,
+	// and ? a single one.
+	NamePatterns []string `json:"name_patterns,omitempty" bson:"name_patterns,omitempty"`
+	Types        []string `json:"types,omitempty" bson:"types,omitempty"`
+
+	// patterns are the compiled NamePatterns, set by compile.
+	patterns []*regexp.Regexp
+}
+
+// Restricted reports whether the scope leaves out some records.
+func (s *Scope) Restricted() bool {
+	return len(s.NamePatterns) > 0 || len(s.Types) > 0
+}
+
+// Matches reports whether the record of the given name and type is in scope.
+// The scope must have been compiled, as the scopes of the accesses returned by
+// ApiKeyAccessService are; otherwise no name matches its patterns.
+func (s *Scope) Matches(name string, recordType string) bool {
+	if len(s.Types) > 0 && !slices.Contains(s.Types, strings.ToUpper(recordType)) {
+		return false
+	}
+
+	if len(s.NamePatterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range s.patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compile compiles the name patterns once for Matches.
+func (s *Scope) compile() error {
+	s.patterns = make([]*regexp.Regexp, 0, len(s.NamePatterns))
+
+	for _, pattern := range s.NamePatterns {
+		compiled, err := regexp.Compile("(?i)" + NamePattern(pattern))
+
+		if err != nil {
+			return fmt.Errorf("invalid name pattern %s: %w", pattern, err)
+		}
+
+		s.patterns = append(s.patterns, compiled)
+	}
+
+	return nil
+}
+
+// NamePattern returns the regular expression matching the record names of
+// the glob, to be matched case-insensitively.
+func NamePattern(glob string) string {
+	var pattern strings.Builder
+
+	pattern.WriteString("^")
+
+	for _, c := range strings.TrimSuffix(glob, ".") {
+		switch c {
+		case '*':
+			pattern.WriteString(".*")
+		case '?':
+			pattern.WriteString(".")
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	pattern.WriteString(`\.?$`)
+
+	return pattern.String()
+}
+
+// normalize validates the scope and upper-cases its types.
+func (s *Scope) normalize() error {
+	for _, pattern := range s.NamePatterns {
+		if strings.TrimSuffix(pattern, ".") == "" {
+			return fmt.Errorf("name patterns must not be empty")
+		}
+	}
+
+	for i, recordType := range s.Types {
+		if recordType == "" {
+			return fmt.Errorf("types must not be empty")
+		}
+
+		s.Types[i] = strings.ToUpper(recordType)
+	}
+
+	return s.compile()
+}
@@ -0,0 +1,34 @@
+package namespace
+
+import "testing"
+
+func TestScopeMatches(t *testing.T) {
+	scope := &Scope{NamePatterns: []string{"*.dev.example.com."}, Types: []string{"a", "txt"}}
+
+	err := scope.normalize()
+
+	if err != nil {
+		t.Fatalf("error normalizing scope: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name       string
+		recordType string
+		matches    bool
+	}{
+		{"api.dev.example.com", "A", true},
+		{"API.Dev.Example.com.", "txt", true},
+		{"a.b.dev.example.com", "TXT", true},
+		{"dev.example.com", "A", false},
+		{"api.dev.example.com", "AAAA", false},
+		{"api.prod.example.com", "A", false},
+	} {
+		if matches := scope.Matches(tt.name, tt.recordType); matches != tt.matches {
+			t.Errorf("expected %s %s to match %v, got %v", tt.name, tt.recordType, tt.matches, matches)
+		}
+	}
+
+	if !(&Scope{}).Matches("example.com", "A") {
+		t.Errorf("expected an empty scope to match every record")
+	}
+}
@@ -0,0 +1,94 @@
+package namespace
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+)
+
+// SelfHandler lets API keys discover what they may do.
+type SelfHandler struct {
+	router        *gin.Engine
+	authenticator *auth.Authenticator
+	service       *ApiKeyAccessService
+}
+
+func NewSelfHandler(router *gin.Engine, authenticator *auth.Authenticator, service *ApiKeyAccessService) *SelfHandler {
+	return &SelfHandler{router: router, authenticator: authenticator, service: service}
+}
+
+func (h *SelfHandler) Register() {
+
+	h.router.GET("/api/v1/self", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		self, err := h.service.Self(ctx, apiKey.ID)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, self)
+	})
+
+	h.router.GET("/api/v1/self/namespaces", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c, time.Second*5)
+		defer cancel()
+
+		apiKey, err := h.authenticator.ValidateApiKeyContext(c, ctx)
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		request, err := pagination.FromQuery(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		namespaces, err := h.service.ListReadable(ctx, apiKey.ID, request)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.JSON(http.StatusOK, namespaces)
+	})
+}
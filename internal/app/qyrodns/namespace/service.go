@@ -4,42 +4,50 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// inactiveIDsTTL bounds how long the inactive namespaces read from the
+// datastore are reused. Status changes made through the service take effect
+// at once; those made by other instances within this delay.
+const inactiveIDsTTL = 5 * time.Second
+
 type Service struct {
-	mongo *mongo.Collection
+	store Store
+
+	mu                sync.Mutex
+	inactiveIDs       map[string]bool
+	inactiveIDsReadAt time.Time
+	// generation is incremented whenever a status changes, so that a read
+	// overtaken by the change does not get cached.
+	generation uint64
 }
 
-func NewService(mongo *mongo.Collection) *Service {
-	return &Service{mongo: mongo}
+func NewService(store Store) *Service {
+	return &Service{store: store}
 }
 
 func (s *Service) Create(ctx context.Context, request *CreationRequest, creatorID string) (*Namespace, error) {
-	nameExists, err := s.nameExists(ctx, request.Name)
-
-	if err != nil {
-		return nil, err
-	}
-
-	if nameExists {
-		return nil, fmt.Errorf("namespace %s already exists", request.Name)
-	}
-
 	namespace := &Namespace{
 		ID:        primitive.NewObjectID(),
 		Name:      request.Name,
+		Status:    StatusActive,
+		Version:   1,
 		CreatorID: creatorID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	_, err = s.mongo.InsertOne(ctx, namespace)
+	err := s.store.Insert(ctx, namespace)
+
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil, fmt.Errorf("namespace %s already exists", request.Name)
+	}
 
 	if err != nil {
 		return nil, err
@@ -48,103 +56,184 @@ func (s *Service) Create(ctx context.Context, request *CreationRequest, creatorI
 	return namespace, nil
 }
 
-func (s *Service) List(ctx context.Context, page int64, size int64) ([]*Namespace, error) {
-	result, err := s.mongo.Find(ctx, bson.M{}, options.Find().SetSkip(page*size).SetLimit(size))
+func (s *Service) List(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	return s.store.List(ctx, request)
+}
+
+// ListByIDs lists the active namespaces among the given ones.
+func (s *Service) ListByIDs(ctx context.Context, namespaceIDs []string, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	ids := make([]primitive.ObjectID, len(namespaceIDs))
+
+	for i, namespaceID := range namespaceIDs {
+		id, err := primitive.ObjectIDFromHex(namespaceID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = id
+	}
+
+	return s.store.ListByIDs(ctx, ids, request)
+}
+
+func (s *Service) Get(ctx context.Context, namespaceID string) (*Namespace, error) {
+	id, err := primitive.ObjectIDFromHex(namespaceID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	namespaces := make([]*Namespace, 0)
+	namespace, err := s.store.Get(ctx, id)
 
-	err = result.All(ctx, &namespaces)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("namespace not found")
+	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	return namespaces, nil
+	return namespace, nil
 }
 
-func (s *Service) Get(ctx context.Context, namespaceID string) (*Namespace, error) {
+// Update renames the namespace. When ifMatch is set, the namespace must still
+// be at that version.
+func (s *Service) Update(ctx context.Context, namespaceID string, request *UpdateRequest, ifMatch *int64) (*Namespace, error) {
 	id, err := primitive.ObjectIDFromHex(namespaceID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	result := s.mongo.FindOne(ctx, bson.M{"_id": id})
+	namespace, err := s.store.Update(ctx, id, request, ifMatch)
+
+	if errors.Is(err, storage.ErrConflict) {
+		return nil, fmt.Errorf("namespace is not at version %d: %w", *ifMatch, err)
+	}
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("namespace not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if errors.Is(err, storage.ErrDuplicate) {
+		return nil, fmt.Errorf("namespace %s already exists", request.Name)
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	var namespace Namespace
+	return namespace, nil
+}
 
-	err = result.Decode(&namespace)
+// Trash moves the namespace to the trash. Its records stop being served but
+// are kept, along with its API key accesses, until it is restored or purged.
+func (s *Service) Trash(ctx context.Context, namespaceID string, ifMatch *int64) (*Namespace, error) {
+	namespace, err := s.getAt(ctx, namespaceID, ifMatch)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &namespace, nil
+	if !namespace.Active() {
+		return nil, fmt.Errorf("namespace is already deleted")
+	}
+
+	now := time.Now()
+
+	return s.setStatus(ctx, namespace, StatusDeleted, &now)
 }
 
-func (s *Service) Update(ctx context.Context, namespaceID string, request *UpdateRequest) (*Namespace, error) {
-	id, err := primitive.ObjectIDFromHex(namespaceID)
+func (s *Service) Restore(ctx context.Context, namespaceID string, ifMatch *int64) (*Namespace, error) {
+	namespace, err := s.getAt(ctx, namespaceID, ifMatch)
 
 	if err != nil {
 		return nil, err
 	}
 
-	fields := bson.M{
-		"updated_at": time.Now(),
+	if namespace.Status != StatusDeleted {
+		return nil, fmt.Errorf("namespace is not in the trash")
 	}
 
-	if request.Name != "" {
-		count, err := s.mongo.CountDocuments(ctx, bson.M{
-			"_id":  bson.M{"$ne": id},
-			"name": request.Name,
-		})
+	return s.setStatus(ctx, namespace, StatusActive, nil)
+}
 
-		if err != nil {
-			return nil, err
-		}
+func (s *Service) ListTrash(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error) {
+	return s.store.ListTrash(ctx, request)
+}
 
-		if count > 0 {
-			return nil, fmt.Errorf("namespace %s already exists", request.Name)
-		}
+// ListExpired returns the namespaces trashed before the given time.
+func (s *Service) ListExpired(ctx context.Context, deletedBefore time.Time) ([]*Namespace, error) {
+	namespaces, err := s.store.ListByStatus(ctx, StatusDeleted)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make([]*Namespace, 0)
 
-		fields["name"] = request.Name
+	for _, namespace := range namespaces {
+		if namespace.DeletedAt != nil && namespace.DeletedAt.Before(deletedBefore) {
+			expired = append(expired, namespace)
+		}
 	}
 
-	result := s.mongo.FindOneAndUpdate(ctx, bson.M{
-		"_id": id,
-	}, bson.M{
-		"$set": fields,
-	})
+	return expired, nil
+}
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
-		return nil, fmt.Errorf("namespace not found")
+// MarkDeleting flags the namespace in the trash for permanent deletion. It
+// fails with storage.ErrConflict when the namespace changed since it was read,
+// for instance because it was restored.
+func (s *Service) MarkDeleting(ctx context.Context, namespace *Namespace) (*Namespace, error) {
+	if namespace.Status != StatusDeleted {
+		return nil, fmt.Errorf("namespace is not in the trash")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	return s.setStatus(ctx, namespace, StatusDeleting, namespace.DeletedAt)
+}
+
+func (s *Service) ListDeleting(ctx context.Context) ([]*Namespace, error) {
+	return s.store.ListByStatus(ctx, StatusDeleting)
+}
+
+// InactiveIDs returns the IDs of the namespaces in the trash or being
+// deleted, whose records must not be served. It is called for every DNS
+// query, so the IDs are kept in memory; the map must not be modified.
+func (s *Service) InactiveIDs(ctx context.Context) (map[string]bool, error) {
+	s.mu.Lock()
+	inactiveIDs, readAt, generation := s.inactiveIDs, s.inactiveIDsReadAt, s.generation
+	s.mu.Unlock()
+
+	if inactiveIDs != nil && time.Since(readAt) < inactiveIDsTTL {
+		return inactiveIDs, nil
 	}
 
-	namespace := &Namespace{}
+	readAt = time.Now()
+	inactiveIDs = make(map[string]bool)
 
-	err = result.Decode(namespace)
+	for _, status := range []Status{StatusDeleted, StatusDeleting} {
+		namespaces, err := s.store.ListByStatus(ctx, status)
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+
+		for _, namespace := range namespaces {
+			inactiveIDs[namespace.ID.Hex()] = true
+		}
 	}
 
-	return namespace, nil
+	s.mu.Lock()
+
+	if s.generation == generation {
+		s.inactiveIDs = inactiveIDs
+		s.inactiveIDsReadAt = readAt
+	}
+
+	s.mu.Unlock()
+
+	return inactiveIDs, nil
 }
 
 func (s *Service) Delete(ctx context.Context, namespaceID string) (*Namespace, error) {
@@ -154,29 +243,42 @@ func (s *Service) Delete(ctx context.Context, namespaceID string) (*Namespace, e
 		return nil, err
 	}
 
-	result := s.mongo.FindOneAndDelete(ctx, bson.M{
-		"_id": id,
-	})
+	namespace, err := s.store.Delete(ctx, id)
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("namespace not found")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if err != nil {
+		return nil, err
 	}
 
-	namespace := &Namespace{}
+	return namespace, nil
+}
 
-	err = result.Decode(namespace)
+// BumpSerial sets the serial of the namespace one past the highest of its
+// serial and floor, and returns the new one.
+func (s *Service) BumpSerial(ctx context.Context, namespaceID string, floor uint32) (uint32, error) {
+	id, err := primitive.ObjectIDFromHex(namespaceID)
 
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	return namespace, nil
+	namespace, err := s.store.BumpSerial(ctx, id, floor)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, fmt.Errorf("namespace not found")
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return namespace.Serial, nil
 }
 
+// Exists reports whether the namespace exists and is active.
 func (s *Service) Exists(ctx context.Context, namespaceID string) (bool, error) {
 	id, err := primitive.ObjectIDFromHex(namespaceID)
 
@@ -184,23 +286,67 @@ func (s *Service) Exists(ctx context.Context, namespaceID string) (bool, error)
 		return false, err
 	}
 
-	count, err := s.mongo.CountDocuments(ctx, bson.M{
-		"_id": id,
-	})
+	namespace, err := s.store.Get(ctx, id)
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
 
 	if err != nil {
 		return false, err
 	}
 
-	return count > 0, nil
+	return namespace.Active(), nil
 }
 
-func (s *Service) nameExists(ctx context.Context, name string) (bool, error) {
-	count, err := s.mongo.CountDocuments(ctx, bson.M{"name": name})
+// getAt returns the namespace, checking it is at the version given by ifMatch
+// when set.
+func (s *Service) getAt(ctx context.Context, namespaceID string, ifMatch *int64) (*Namespace, error) {
+	namespace, err := s.Get(ctx, namespaceID)
 
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+
+	if ifMatch != nil && *ifMatch != namespace.Version {
+		return nil, fmt.Errorf("namespace is at version %d: %w", namespace.Version, storage.ErrConflict)
+	}
+
+	return namespace, nil
+}
+
+// setStatus changes the status of the namespace, provided it has not changed
+// since it was read.
+func (s *Service) setStatus(ctx context.Context, namespace *Namespace, status Status, deletedAt *time.Time) (*Namespace, error) {
+	from := namespace.Status
+
+	if namespace.Active() {
+		from = StatusActive
+	}
+
+	namespace, err := s.store.SetStatus(ctx, namespace.ID, from, namespace.Version, status, deletedAt)
+
+	s.invalidateInactiveIDs()
+
+	if errors.Is(err, storage.ErrConflict) {
+		return nil, fmt.Errorf("namespace changed before its status could be set to %s: %w", status, err)
+	}
+
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("namespace not found")
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	return count > 0, nil
+	return namespace, nil
+}
+
+func (s *Service) invalidateInactiveIDs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	s.inactiveIDs = nil
 }
@@ -0,0 +1,51 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/pagination"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Store interface {
+	Insert(ctx context.Context, namespace *Namespace) error
+	// List returns the active namespaces.
+	List(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error)
+	// ListByIDs returns the active namespaces among the given ones.
+	ListByIDs(ctx context.Context, ids []primitive.ObjectID, request *pagination.Request) (*pagination.Page[Namespace], error)
+	// ListTrash returns the namespaces in the trash.
+	ListTrash(ctx context.Context, request *pagination.Request) (*pagination.Page[Namespace], error)
+	Get(ctx context.Context, id primitive.ObjectID) (*Namespace, error)
+	// Update fails with storage.ErrConflict when version is set and the
+	// namespace is no longer at that version.
+	Update(ctx context.Context, id primitive.ObjectID, request *UpdateRequest, version *int64) (*Namespace, error)
+	// SetStatus only changes the namespace while it has the status from and
+	// is at the version, and fails with storage.ErrConflict otherwise.
+	SetStatus(ctx context.Context, id primitive.ObjectID, from Status, version int64, status Status, deletedAt *time.Time) (*Namespace, error)
+	ListByStatus(ctx context.Context, status Status) ([]*Namespace, error)
+	// BumpSerial sets the serial one past the highest of the current serial
+	// and floor.
+	BumpSerial(ctx context.Context, id primitive.ObjectID, floor uint32) (*Namespace, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (*Namespace, error)
+}
+
+type ApiKeyAccessStore interface {
+	// AddActions grants the actions, replacing the scope of the access when
+	// one is given.
+	AddActions(ctx context.Context, namespaceID string, apiKeyID string, actions []Action, scope *Scope, creatorID string) error
+	RemoveActions(ctx context.Context, namespaceID string, apiKeyID string, actions []Action) error
+	List(ctx context.Context, namespaceID string, request *pagination.Request) (*pagination.Page[ApiKeyAccess], error)
+	Delete(ctx context.Context, namespaceID string, apiKeyID string) error
+	Get(ctx context.Context, namespaceID string, apiKeyID string) (*ApiKeyAccess, error)
+	ListByApiKeyID(ctx context.Context, apiKeyID string) ([]*ApiKeyAccess, error)
+	DeleteByNamespaceID(ctx context.Context, namespaceID string) error
+}
+
+func cursor(namespace *Namespace) pagination.Cursor {
+	return pagination.Cursor{ID: namespace.ID}
+}
+
+func accessCursor(access *ApiKeyAccess) pagination.Cursor {
+	return pagination.Cursor{ID: access.ID}
+}
@@ -0,0 +1,299 @@
+// Package qyrodnstest boots a complete QyroDNS server backed by the in-memory
+// storage on ephemeral ports, for end-to-end tests of the admin API and the
+// DNS server.
+package qyrodnstest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/miekg/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+)
+
+const (
+	AdminUsername = "admin"
+	AdminPassword = "password"
+)
+
+type Harness struct {
+	t          testing.TB
+	Server     *qyrodns.Server
+	AdminURL   string
+	DNSAddr    string
+	httpClient *http.Client
+	dnsClient  *dns.Client
+	adminToken string
+}
+
+// New starts a server and stops it when the test finishes. The config, if
+// given, is used as a base; addresses and the storage backend are always
+// overridden.
+func New(t testing.TB, configs ...*qyrodns.ServerConfig) *Harness {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	config := &qyrodns.ServerConfig{
+		JwtSigningKey: "secret",
+		JwtIssuer:     "qyrodns",
+		JwtAudience:   "qyrodns",
+	}
+
+	if len(configs) > 0 {
+		config = configs[0]
+	}
+
+	config.DNSHost = "127.0.0.1"
+	config.DNSPort = "0"
+	config.AdminHost = "127.0.0.1"
+	config.AdminPort = "0"
+	config.StorageBackend = string(storage.BackendMemory)
+
+	if config.JwtAlgorithm == "" {
+		config.JwtAlgorithm = "HS256"
+	}
+
+	if config.JwtKeyRotationInterval == 0 {
+		config.JwtKeyRotationInterval = time.Hour
+	}
+
+	if config.AdminAccessTokenTTL == 0 {
+		config.AdminAccessTokenTTL = time.Hour
+	}
+
+	if config.AdminRefreshTokenTTL == 0 {
+		config.AdminRefreshTokenTTL = time.Hour
+	}
+
+	if config.DNSSnapshotInterval == 0 {
+		config.DNSSnapshotInterval = time.Minute
+	}
+
+	if config.DNSMaxStaleness == 0 {
+		config.DNSMaxStaleness = time.Hour
+	}
+
+	if config.TrashRetention == 0 {
+		config.TrashRetention = time.Hour
+	}
+
+	if config.TrashPurgeInterval == 0 {
+		config.TrashPurgeInterval = time.Minute
+	}
+
+	server := qyrodns.NewServer(config)
+
+	err := server.Listen()
+
+	if err != nil {
+		t.Fatalf("error starting server: %v", err)
+	}
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- server.Serve()
+	}()
+
+	h := &Harness{
+		t:          t,
+		Server:     server,
+		AdminURL:   "http://" + server.AdminAddr(),
+		DNSAddr:    server.DNSAddr(),
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		dnsClient:  &dns.Client{Net: "udp", Timeout: time.Second},
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := server.Shutdown(ctx)
+
+		if err != nil {
+			t.Errorf("error shutting down server: %v", err)
+		}
+
+		err = <-served
+
+		if err != nil {
+			t.Errorf("error serving: %v", err)
+		}
+	})
+
+	h.waitForDNS()
+
+	return h
+}
+
+// Do sends a JSON request to the admin API, decodes the response into out
+// when it is not nil and returns the status code.
+func (h *Harness) Do(method string, path string, authorization string, body any, out any) int {
+	h.t.Helper()
+
+	header := http.Header{}
+
+	if authorization != "" {
+		header.Set("Authorization", authorization)
+	}
+
+	status, _ := h.DoWithHeader(method, path, header, body, out)
+
+	return status
+}
+
+// DoWithHeader is Do sending the given request headers and also returning the
+// response headers.
+func (h *Harness) DoWithHeader(method string, path string, header http.Header, body any, out any) (int, http.Header) {
+	h.t.Helper()
+
+	var reader io.Reader
+
+	if body != nil {
+		data, err := json.Marshal(body)
+
+		if err != nil {
+			h.t.Fatalf("error encoding request body: %v", err)
+		}
+
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, h.AdminURL+path, reader)
+
+	if err != nil {
+		h.t.Fatalf("error creating request: %v", err)
+	}
+
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := h.httpClient.Do(request)
+
+	if err != nil {
+		h.t.Fatalf("error sending %s %s: %v", method, path, err)
+	}
+
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+
+	if err != nil {
+		h.t.Fatalf("error reading response of %s %s: %v", method, path, err)
+	}
+
+	if out != nil {
+		err = json.Unmarshal(data, out)
+
+		if err != nil {
+			h.t.Fatalf("error decoding response of %s %s (%d %s): %v", method, path, response.StatusCode, data, err)
+		}
+	}
+
+	return response.StatusCode, response.Header
+}
+
+// MustDo is Do failing the test unless the status code is the expected one.
+func (h *Harness) MustDo(expectedStatus int, method string, path string, authorization string, body any, out any) {
+	h.t.Helper()
+
+	var raw json.RawMessage
+
+	status := h.Do(method, path, authorization, body, &raw)
+
+	if status != expectedStatus {
+		h.t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, expectedStatus, status, raw)
+	}
+
+	if out != nil {
+		err := json.Unmarshal(raw, out)
+
+		if err != nil {
+			h.t.Fatalf("error decoding response of %s %s: %v", method, path, err)
+		}
+	}
+}
+
+// AdminToken initialises the first admin on first use and returns a bearer
+// authorization header for it.
+func (h *Harness) AdminToken() string {
+	h.t.Helper()
+
+	if h.adminToken != "" {
+		return h.adminToken
+	}
+
+	credentials := map[string]string{"username": AdminUsername, "password": AdminPassword}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/admins/init", "", credentials, nil)
+
+	var token struct {
+		Token string `json:"token"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/api/v1/admins/token", "", credentials, &token)
+
+	h.adminToken = Bearer(token.Token)
+
+	return h.adminToken
+}
+
+// Query sends a DNS query for the name and type to the server.
+func (h *Harness) Query(name string, qtype uint16) *dns.Msg {
+	h.t.Helper()
+
+	response, err := h.exchange(name, qtype)
+
+	if err != nil {
+		h.t.Fatalf("error querying %s %s: %v", name, dns.TypeToString[qtype], err)
+	}
+
+	return response
+}
+
+func (h *Harness) exchange(name string, qtype uint16) (*dns.Msg, error) {
+	message := new(dns.Msg)
+	message.SetQuestion(dns.Fqdn(name), qtype)
+
+	response, _, err := h.dnsClient.Exchange(message, h.DNSAddr)
+
+	return response, err
+}
+
+func (h *Harness) waitForDNS() {
+	h.t.Helper()
+
+	var err error
+
+	for range 50 {
+		_, err = h.exchange("qyrodns.test", dns.TypeA)
+
+		if err == nil {
+			return
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	h.t.Fatalf("DNS server did not start: %v", err)
+}
+
+func Bearer(token string) string {
+	return fmt.Sprintf("Bearer %s", token)
+}
+
+func ApiKey(secret string) string {
+	return fmt.Sprintf("ApiKey %s", secret)
+}
@@ -2,10 +2,15 @@ package qyrodns
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/miekg/dns"
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/apikey"
@@ -13,13 +18,20 @@ import (
 	dnsLib "github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/health"
 	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/signingkey"
 	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"github.com/qyrocloud/qyrodns/internal/pkg/migration"
 )
 
 type Server struct {
 	config *ServerConfig
+
+	stores      *stores
+	dnsConn     net.PacketConn
+	dnsServer   *dns.Server
+	adminLn     net.Listener
+	adminServer *http.Server
+	cancel      context.CancelFunc
 }
 
 func NewServer(config *ServerConfig) *Server {
@@ -27,78 +39,267 @@ func NewServer(config *ServerConfig) *Server {
 }
 
 type ServerConfig struct {
-	DNSHost       string
-	DNSPort       string
-	AdminHost     string
-	AdminPort     string
-	MongoEndpoint string
-	MongoDatabase string
-	JwtSigningKey string
-	JwtIssuer     string
-	JwtAudience   string
+	DNSHost        string
+	DNSPort        string
+	AdminHost      string
+	AdminPort      string
+	StorageBackend string
+	MongoEndpoint  string
+	MongoDatabase  string
+	BoltPath       string
+	JwtSigningKey  string
+	JwtIssuer      string
+	JwtAudience    string
+	TrustedProxies []string
+
+	// MongoAllowStandalone lets the server write without transactions on a
+	// standalone MongoDB server instead of refusing to start.
+	MongoAllowStandalone bool
+
+	JwtAlgorithm           string
+	JwtKeyRotationInterval time.Duration
+
+	AdminAccessTokenTTL  time.Duration
+	AdminRefreshTokenTTL time.Duration
+
+	ApiKeySecretOverlap time.Duration
+
+	DNSSnapshotPath     string
+	DNSSnapshotInterval time.Duration
+	DNSMaxStaleness     time.Duration
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
 }
 
+// Start sets up and runs the server, exiting the process on failure.
 func (s *Server) Start() {
-	// Database setup
+	err := s.Listen()
+
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(s.config.MongoEndpoint))
+	err = s.Serve()
 
 	if err != nil {
-		log.Fatal("error while connecting to mongo database: ", err)
+		log.Fatal(err)
 	}
+}
 
-	if err := client.Ping(context.Background(), nil); err != nil {
-		log.Fatal("error while pinging mongo database: ", err)
+// Listen opens the storage, wires the services and binds the DNS and admin
+// sockets without serving yet. Ports set to "0" are picked by the system and
+// can be read back with DNSAddr and AdminAddr.
+func (s *Server) Listen() error {
+	// Database setup
+
+	stores, err := s.openStores()
+
+	if err != nil {
+		return fmt.Errorf("error while opening %s storage: %w", s.config.StorageBackend, err)
 	}
 
-	mongoDatabase := client.Database(s.config.MongoDatabase)
+	s.stores = stores
+
+	datastoreUnavailable := stores.ping(context.Background())
+
+	if datastoreUnavailable == nil {
+		err = stores.checkTransactions(context.Background())
+
+		if err != nil {
+			return fmt.Errorf("error while checking %s storage: %w", s.config.StorageBackend, err)
+		}
+
+		version, err := migration.NewRunner(stores.migrations, migrations(stores)).Run(context.Background())
+
+		if err != nil {
+			return fmt.Errorf("error while migrating database: %w", err)
+		}
+
+		log.Printf("database schema at version %d", version)
+	} else {
+		log.Printf("skipping database migrations, database is unavailable")
+	}
 
 	// Services setup
 
-	apiKeysCollection := mongoDatabase.Collection("api_keys")
-	authenticator := auth.NewAuthenticator(s.config.JwtSigningKey, s.config.JwtIssuer, s.config.JwtAudience, apiKeysCollection)
-	adminService := admin.NewService(mongoDatabase.Collection("admins"), authenticator)
-	apiKeyService := apikey.NewService(apiKeysCollection)
-	namespaceService := namespace.NewService(mongoDatabase.Collection("namespaces"))
-	apiKeyAccessService := namespace.NewApiKeyAccessService(mongoDatabase.Collection("api_key_accesses"), namespaceService, apiKeyService)
-	recordService := dnsLib.NewRecordService(mongoDatabase.Collection("records"), namespaceService)
+	keys, signingKeyService, err := s.signingKeys(stores, datastoreUnavailable)
+
+	if err != nil {
+		return err
+	}
+
+	authenticator := auth.NewAuthenticator(keys, s.config.JwtIssuer, s.config.JwtAudience, s.config.AdminAccessTokenTTL, stores.apiKeys)
+	adminService := admin.NewService(stores.admins, stores.transactor, authenticator, s.config.AdminRefreshTokenTTL)
+	adminAuthorizer := admin.NewAuthorizer(authenticator, adminService)
+	apiKeyService := apikey.NewService(stores.apiKeys, s.config.ApiKeySecretOverlap)
+	namespaceService := namespace.NewService(stores.namespaces)
+	apiKeyAccessService := namespace.NewApiKeyAccessService(stores.apiKeyAccesses, namespaceService, apiKeyService)
+	recordService := dnsLib.NewRecordService(stores.records, stores.recordRevisions, stores.transactor, namespaceService)
 
 	// DNS server setup
 
-	dnsHandler := dnsLib.NewHandler(recordService)
-	dns.HandleFunc(".", dnsHandler.Handle)
+	staleCache, err := dnsLib.NewStaleCache(recordService, s.config.DNSSnapshotPath, s.config.DNSSnapshotInterval, s.config.DNSMaxStaleness)
 
-	dnsServer := &dns.Server{
-		Addr: fmt.Sprintf("%s:%s", s.config.DNSHost, s.config.DNSPort),
-		Net:  "udp",
+	if err != nil {
+		return err
 	}
 
-	go func() {
-		log.Printf("starting DNS server on %s:%s", s.config.DNSHost, s.config.DNSPort)
-		err = dnsServer.ListenAndServe()
+	if datastoreUnavailable != nil {
+		if s.config.DNSSnapshotPath == "" {
+			return fmt.Errorf("error while pinging database: %w", datastoreUnavailable)
+		}
 
-		if err != nil {
-			log.Fatal("error while starting DNS server: ", err)
+		if err := staleCache.Load(); err != nil {
+			return fmt.Errorf("error while pinging database: %w (snapshot unavailable: %v)", datastoreUnavailable, err)
 		}
-	}()
+
+		log.Printf("error while pinging database: %v, starting from snapshot %s", datastoreUnavailable, s.config.DNSSnapshotPath)
+		staleCache.MarkDegraded()
+	}
+
+	namespaceDeletionJob := deletion.NewNamespaceDeletionJob(namespaceService, apiKeyAccessService, recordService, time.Minute)
+	purger, err := deletion.NewPurger(namespaceService, recordService, namespaceDeletionJob, s.config.TrashRetention, s.config.TrashPurgeInterval)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	go staleCache.Run(ctx)
+
+	if signingKeyService != nil {
+		go signingKeyService.Run(ctx)
+	}
+
+	go namespaceDeletionJob.Run(ctx)
+	go purger.Run(ctx)
+
+	dnsHandler := dnsLib.NewHandler(recordService, staleCache)
+	dnsMux := dns.NewServeMux()
+	dnsMux.HandleFunc(".", dnsHandler.Handle)
+
+	s.dnsConn, err = net.ListenPacket("udp", net.JoinHostPort(s.config.DNSHost, s.config.DNSPort))
+
+	if err != nil {
+		return fmt.Errorf("error while starting DNS server: %w", err)
+	}
+
+	s.dnsServer = &dns.Server{
+		PacketConn: s.dnsConn,
+		Net:        "udp",
+		Handler:    dnsMux,
+	}
 
 	// Admin server setup
 
 	router := gin.Default()
-	health.NewCheckHandler(router).Register()
-	admin.NewHandler(router, authenticator, adminService).Register()
-	apikey.NewHandler(router, authenticator, apiKeyService).Register()
-	namespace.NewHandler(router, authenticator, namespaceService).Register()
-	deletion.NewNamespaceDeletionHandler(router, authenticator, namespaceService, apiKeyAccessService, recordService).Register()
-	namespace.NewApiKeyAccessHandler(router, authenticator, apiKeyAccessService).Register()
-	dnsLib.NewRecordAdminHandler(router, authenticator, recordService).Register()
+
+	err = router.SetTrustedProxies(s.config.TrustedProxies)
+
+	if err != nil {
+		return fmt.Errorf("error setting trusted proxies: %w", err)
+	}
+
+	health.NewCheckHandler(router, staleCache).Register()
+	signingkey.NewJWKSHandler(router, signingKeyService).Register()
+	admin.NewHandler(router, adminAuthorizer, adminService).Register()
+	apikey.NewHandler(router, adminAuthorizer, apiKeyService).Register()
+	namespace.NewHandler(router, adminAuthorizer, namespaceService).Register()
+	deletion.NewNamespaceDeletionHandler(router, adminAuthorizer, namespaceService).Register()
+	namespace.NewApiKeyAccessHandler(router, adminAuthorizer, apiKeyAccessService).Register()
+	namespace.NewSelfHandler(router, authenticator, apiKeyAccessService).Register()
+	dnsLib.NewRecordAdminHandler(router, adminAuthorizer, recordService).Register()
 	dnsLib.NewRecordHandler(router, authenticator, apiKeyAccessService, recordService).Register()
 
-	log.Printf("starting admin server on %s:%s", s.config.AdminHost, s.config.AdminPort)
+	s.adminLn, err = net.Listen("tcp", net.JoinHostPort(s.config.AdminHost, s.config.AdminPort))
+
+	if err != nil {
+		return fmt.Errorf("error starting admin server: %w", err)
+	}
 
-	err = router.Run(fmt.Sprintf("%s:%s", s.config.AdminHost, s.config.AdminPort))
+	s.adminServer = &http.Server{Handler: router.Handler()}
+
+	return nil
+}
+
+// signingKeys returns the keys admin tokens are signed with: the HMAC secret
+// for HS256, or keys of the asymmetric algorithm rotated by the returned
+// service otherwise. Keys are loaded right away when the datastore is up so
+// that tokens can be issued as soon as the server listens.
+func (s *Server) signingKeys(stores *stores, datastoreUnavailable error) (auth.Keys, *signingkey.Service, error) {
+	if s.config.JwtAlgorithm == jwt.SigningMethodHS256.Alg() {
+		return auth.NewHMACKeys(s.config.JwtSigningKey), nil, nil
+	}
+
+	service, err := signingkey.NewService(stores.signingKeys, s.config.JwtAlgorithm, s.config.JwtKeyRotationInterval, s.config.AdminAccessTokenTTL)
 
 	if err != nil {
-		log.Fatal("error starting admin server: ", err)
+		return nil, nil, err
+	}
+
+	if datastoreUnavailable == nil {
+		err := service.Rotate(context.Background())
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("error while loading signing keys: %w", err)
+		}
 	}
+
+	return service, service, nil
+}
+
+// Serve answers DNS queries and admin API requests until Shutdown is called
+// or one of the servers fails.
+func (s *Server) Serve() error {
+	errs := make(chan error, 2)
+
+	go func() {
+		log.Printf("starting DNS server on %s", s.DNSAddr())
+
+		err := s.dnsServer.ActivateAndServe()
+
+		if err != nil {
+			errs <- fmt.Errorf("error while starting DNS server: %w", err)
+			return
+		}
+
+		errs <- nil
+	}()
+
+	go func() {
+		log.Printf("starting admin server on %s", s.AdminAddr())
+
+		err := s.adminServer.Serve(s.adminLn)
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("error starting admin server: %w", err)
+			return
+		}
+
+		errs <- nil
+	}()
+
+	return <-errs
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	err := errors.Join(
+		s.adminServer.Shutdown(ctx),
+		s.dnsServer.ShutdownContext(ctx),
+	)
+
+	return errors.Join(err, s.stores.close())
+}
+
+func (s *Server) DNSAddr() string {
+	return s.dnsConn.LocalAddr().String()
+}
+
+func (s *Server) AdminAddr() string {
+	return s.adminLn.Addr().String()
 }
@@ -0,0 +1,1826 @@
+package qyrodns_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/miekg/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/apikey"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/qyrodnstest"
+	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type namespaceResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type recordResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	TTL   uint32 `json:"ttl"`
+}
+
+type recordPage struct {
+	Items      []recordResponse `json:"items"`
+	NextCursor string           `json:"next_cursor"`
+	Total      int64            `json:"total"`
+}
+
+type revisionPage struct {
+	Items []struct {
+		Version int64  `json:"version"`
+		Action  string `json:"action"`
+	} `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	Total      int64  `json:"total"`
+}
+
+func createNamespace(h *qyrodnstest.Harness, name string) string {
+	var namespace namespaceResponse
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/namespaces", h.AdminToken(), map[string]any{"name": name}, &namespace)
+
+	return namespace.ID
+}
+
+func createRecord(h *qyrodnstest.Harness, namespaceID string, name string, recordType string, value string) string {
+	var record recordResponse
+
+	h.MustDo(http.StatusCreated, http.MethodPost, fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID), h.AdminToken(), map[string]any{
+		"name":  name,
+		"type":  recordType,
+		"value": value,
+		"ttl":   60,
+		"class": "IN",
+	}, &record)
+
+	return record.ID
+}
+
+func TestAdminInitOnlyOnce(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	h.AdminToken()
+
+	status := h.Do(http.MethodPost, "/api/v1/admins/init", "", map[string]string{"username": "other", "password": "other"}, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected second init to be rejected, got %d", status)
+	}
+
+	var current struct {
+		Username string `json:"username"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", h.AdminToken(), nil, &current)
+
+	if current.Username != qyrodnstest.AdminUsername {
+		t.Fatalf("expected current admin %s, got %s", qyrodnstest.AdminUsername, current.Username)
+	}
+}
+
+func TestRecordsAreServed(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+	createRecord(h, namespaceID, "example.com", "A", "192.168.0.106")
+	createRecord(h, namespaceID, "example.com", "MX", "10 mail.example.com")
+	createRecord(h, namespaceID, "www.example.com", "CNAME", "example.github.io")
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeSuccess || len(response.Answer) != 2 {
+		t.Fatalf("expected two A answers, got %v", response)
+	}
+
+	response = h.Query("example.com", dns.TypeMX)
+
+	if len(response.Answer) != 1 || response.Answer[0].(*dns.MX).Mx != "mail.example.com." {
+		t.Fatalf("expected MX answer, got %v", response)
+	}
+
+	response = h.Query("www.example.com", dns.TypeCNAME)
+
+	if len(response.Answer) != 1 || response.Answer[0].(*dns.CNAME).Target != "example.github.io." {
+		t.Fatalf("expected CNAME answer, got %v", response)
+	}
+
+	response = h.Query("missing.example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN, got %v", response)
+	}
+}
+
+func TestRecordUpdateAndDeletion(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	recordID := createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+	recordPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records/%s", namespaceID, recordID)
+
+	var record recordResponse
+
+	h.MustDo(http.StatusOK, http.MethodPut, recordPath, h.AdminToken(), map[string]any{"value": "10.0.0.1"}, &record)
+
+	if record.Value != "10.0.0.1" || record.TTL != 60 {
+		t.Fatalf("unexpected updated record %+v", record)
+	}
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if len(response.Answer) != 1 || response.Answer[0].(*dns.A).A.String() != "10.0.0.1" {
+		t.Fatalf("expected updated answer, got %v", response)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodDelete, recordPath, h.AdminToken(), nil, nil)
+
+	response = h.Query("example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN after deletion, got %v", response)
+	}
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+	DNS    struct {
+		Degraded        bool       `json:"degraded"`
+		SnapshotTakenAt *time.Time `json:"snapshot_taken_at"`
+	} `json:"dns"`
+}
+
+func TestStaleAnswersAreServed(t *testing.T) {
+	h := qyrodnstest.New(t, &qyrodns.ServerConfig{
+		JwtSigningKey:       "secret",
+		JwtIssuer:           "qyrodns",
+		JwtAudience:         "qyrodns",
+		DNSSnapshotInterval: 10 * time.Millisecond,
+		DNSMaxStaleness:     time.Second,
+	})
+
+	namespaceID := createNamespace(h, "example")
+	createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+	createdAt := time.Now()
+
+	var health healthResponse
+
+	for range 100 {
+		h.MustDo(http.StatusOK, http.MethodGet, "/health", "", nil, &health)
+
+		if health.DNS.SnapshotTakenAt != nil && health.DNS.SnapshotTakenAt.After(createdAt) {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if health.Status != "ok" || health.DNS.SnapshotTakenAt == nil || !health.DNS.SnapshotTakenAt.After(createdAt) {
+		t.Fatalf("expected a healthy snapshot holding the record, got %+v", health)
+	}
+
+	err := h.Server.CloseDatastore()
+
+	if err != nil {
+		t.Fatalf("error closing datastore: %v", err)
+	}
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeSuccess || len(response.Answer) != 1 || response.Answer[0].(*dns.A).A.String() != "192.168.0.105" {
+		t.Fatalf("expected stale answer, got %v", response)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/health", "", nil, &health)
+
+	if health.Status != "degraded" || !health.DNS.Degraded {
+		t.Fatalf("expected degraded health, got %+v", health)
+	}
+
+	metrics, err := http.Get(h.AdminURL + "/metrics")
+
+	if err != nil {
+		t.Fatalf("error getting metrics: %v", err)
+	}
+
+	body, err := io.ReadAll(metrics.Body)
+	metrics.Body.Close()
+
+	if err != nil {
+		t.Fatalf("error reading metrics: %v", err)
+	}
+
+	if !strings.Contains(string(body), "qyrodns_dns_degraded 1\n") || !strings.Contains(string(body), "qyrodns_dns_snapshot_age_seconds ") {
+		t.Fatalf("expected degraded metrics with the snapshot age, got %s", body)
+	}
+
+	time.Sleep(time.Until(health.DNS.SnapshotTakenAt.Add(time.Second + 100*time.Millisecond)))
+
+	response = h.Query("example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeServerFailure || len(response.Answer) != 0 {
+		t.Fatalf("expected SERVFAIL past the maximum staleness, got %v", response)
+	}
+}
+
+func TestApiKeyAccess(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+
+	var apiKey struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{"name": "ci"}, &apiKey)
+
+	secret := apiKey.Secret
+
+	recordsPath := fmt.Sprintf("/api/v1/namespaces/%s/records", namespaceID)
+	record := map[string]any{"name": "ci.example.com", "type": "TXT", "value": "hello", "ttl": 60, "class": "IN"}
+
+	status := h.Do(http.MethodPost, recordsPath, qyrodnstest.ApiKey(secret), record, nil)
+
+	if status != http.StatusForbidden {
+		t.Fatalf("expected forbidden without access, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+		"api_key_id": apiKey.ID,
+		"actions":    []string{"create", "read"},
+	}, nil)
+
+	var created recordResponse
+
+	h.MustDo(http.StatusCreated, http.MethodPost, recordsPath, qyrodnstest.ApiKey(secret), record, &created)
+
+	var records recordPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(secret), nil, &records)
+
+	if len(records.Items) != 1 || records.Items[0].ID != created.ID {
+		t.Fatalf("expected the created record to be listed, got %+v", records)
+	}
+
+	status = h.Do(http.MethodDelete, fmt.Sprintf("%s/%s", recordsPath, created.ID), qyrodnstest.ApiKey(secret), nil, nil)
+
+	if status != http.StatusForbidden {
+		t.Fatalf("expected forbidden deletion, got %d", status)
+	}
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey("invalid"), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized with invalid secret, got %d", status)
+	}
+}
+
+func TestApiKeyScopes(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	challengeID := createRecord(h, namespaceID, "_acme-challenge.example.com", "TXT", "token")
+	otherTXTID := createRecord(h, namespaceID, "example.com", "TXT", "v=spf1 -all")
+	createRecord(h, namespaceID, "_acme-challenge.www.example.com", "A", "192.168.0.105")
+
+	var apiKey struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{"name": "acme"}, &apiKey)
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+		"api_key_id": apiKey.ID,
+		"actions":    []string{"create", "read", "update", "delete"},
+		"scope":      map[string]any{"name_patterns": []string{"_acme-challenge.*"}, "types": []string{"txt"}},
+	}, nil)
+
+	secret := qyrodnstest.ApiKey(apiKey.Secret)
+	recordsPath := fmt.Sprintf("/api/v1/namespaces/%s/records", namespaceID)
+
+	var records recordPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, secret, nil, &records)
+
+	if len(records.Items) != 1 || records.Items[0].ID != challengeID || records.Total != 1 {
+		t.Fatalf("expected only the challenge record to be listed, got %+v", records)
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, recordsPath, secret, map[string]any{
+		"name": "_acme-challenge.www.example.com", "type": "TXT", "value": "other", "ttl": 60, "class": "IN",
+	}, nil)
+
+	forbidden := map[string]func() int{
+		"creating another type": func() int {
+			return h.Do(http.MethodPost, recordsPath, secret, map[string]any{
+				"name": "_acme-challenge.example.com", "type": "CNAME", "value": "example.org.", "ttl": 60, "class": "IN",
+			}, nil)
+		},
+		"reading another record": func() int {
+			return h.Do(http.MethodGet, fmt.Sprintf("%s/%s", recordsPath, otherTXTID), secret, nil, nil)
+		},
+		"renaming out of scope": func() int {
+			return h.Do(http.MethodPut, fmt.Sprintf("%s/%s", recordsPath, challengeID), secret, map[string]any{"name": "example.com"}, nil)
+		},
+		"deleting another record": func() int {
+			return h.Do(http.MethodDelete, fmt.Sprintf("%s/%s", recordsPath, otherTXTID), secret, nil, nil)
+		},
+		"changing another record": func() int {
+			return h.Do(http.MethodPost, recordsPath+"/changes", secret, map[string]any{
+				"changes": []map[string]any{{"action": "delete", "record_id": otherTXTID}},
+			}, nil)
+		},
+		"reading another rrset": func() int {
+			return h.Do(http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s/rrsets/example.com/TXT", namespaceID), secret, nil, nil)
+		},
+		"linting": func() int {
+			return h.Do(http.MethodGet, recordsPath+"/lint", secret, nil, nil)
+		},
+	}
+
+	for operation, do := range forbidden {
+		if status := do(); status != http.StatusForbidden {
+			t.Fatalf("expected %s to be forbidden, got %d", operation, status)
+		}
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, fmt.Sprintf("%s/%s", recordsPath, challengeID), secret, map[string]any{"value": "renewed"}, nil)
+
+	// Records out of scope are left out of syncs rather than deleted.
+	var plan struct {
+		Changes []map[string]any `json:"changes"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, recordsPath+"/sync", secret, map[string]any{
+		"records": []map[string]any{
+			{"name": "_acme-challenge.example.com", "type": "TXT", "value": "renewed", "ttl": 60, "class": "IN"},
+		},
+	}, &plan)
+
+	if len(plan.Changes) != 1 || plan.Changes[0]["action"] != "delete" {
+		t.Fatalf("expected only the other challenge record to be deleted, got %+v", plan.Changes)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID), h.AdminToken(), nil, &records)
+
+	if records.Total != 3 {
+		t.Fatalf("expected the records out of scope to be kept, got %+v", records)
+	}
+}
+
+func TestApiKeySelf(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	readableID := createNamespace(h, "readable")
+	writableID := createNamespace(h, "writable")
+	trashedID := createNamespace(h, "trashed")
+	createNamespace(h, "other")
+
+	var apiKey struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{"name": "ci"}, &apiKey)
+
+	grants := map[string][]string{readableID: {"read"}, writableID: {"create"}, trashedID: {"read"}}
+
+	for namespaceID, actions := range grants {
+		h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+			"api_key_id": apiKey.ID,
+			"actions":    actions,
+		}, nil)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s", trashedID), h.AdminToken(), nil, nil)
+
+	secret := qyrodnstest.ApiKey(apiKey.Secret)
+
+	var self struct {
+		ApiKey struct {
+			ID string `json:"id"`
+		} `json:"api_key"`
+		Namespaces []struct {
+			Namespace namespaceResponse `json:"namespace"`
+			Actions   []string          `json:"actions"`
+		} `json:"namespaces"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/self", secret, nil, &self)
+
+	if self.ApiKey.ID != apiKey.ID || len(self.Namespaces) != 2 {
+		t.Fatalf("expected the api key and its two active namespaces, got %+v", self)
+	}
+
+	for _, access := range self.Namespaces {
+		if !slices.Equal(access.Actions, grants[access.Namespace.ID]) {
+			t.Fatalf("expected the actions of %s to be %v, got %v", access.Namespace.Name, grants[access.Namespace.ID], access.Actions)
+		}
+	}
+
+	var namespaces struct {
+		Items []namespaceResponse `json:"items"`
+		Total int64               `json:"total"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/self/namespaces", secret, nil, &namespaces)
+
+	if namespaces.Total != 1 || len(namespaces.Items) != 1 || namespaces.Items[0].ID != readableID {
+		t.Fatalf("expected only the readable namespace to be listed, got %+v", namespaces)
+	}
+
+	status := h.Do(http.MethodGet, "/api/v1/self", h.AdminToken(), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected admins to be rejected, got %d", status)
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	assignedID := createNamespace(h, "assigned")
+	otherID := createNamespace(h, "other")
+
+	auditorID, auditor := addAdmin(h, "auditor", map[string]any{"role": "auditor"})
+	_, namespaceAdmin := addAdmin(h, "namespace-admin", map[string]any{"role": "namespace-admin", "namespace_id": assignedID})
+
+	h.MustDo(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/api/v1/namespaces/%s/records", otherID), auditor, nil, nil)
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins", auditor, nil, nil)
+
+	record := map[string]any{"name": "example.com", "type": "A", "value": "192.168.0.105", "ttl": 60, "class": "IN"}
+
+	denied := []struct {
+		authorization string
+		method        string
+		path          string
+		body          any
+	}{
+		{auditor, http.MethodPost, fmt.Sprintf("/admin/api/v1/namespaces/%s/records", assignedID), record},
+		{auditor, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s", assignedID), nil},
+		{auditor, http.MethodPost, "/api/v1/api-keys", map[string]any{"name": "ci"}},
+		{namespaceAdmin, http.MethodPost, fmt.Sprintf("/admin/api/v1/namespaces/%s/records", otherID), record},
+		{namespaceAdmin, http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s", otherID), nil},
+		{namespaceAdmin, http.MethodPost, "/api/v1/namespaces", map[string]any{"name": "new"}},
+		{namespaceAdmin, http.MethodPost, "/admin/api/v1/records/replace", map[string]any{"value": "192.168.0.105", "replacement": "192.168.0.106"}},
+		{namespaceAdmin, http.MethodDelete, fmt.Sprintf("/api/v1/admins/%s", auditorID), nil},
+	}
+
+	for _, request := range denied {
+		status := h.Do(request.method, request.path, request.authorization, request.body, nil)
+
+		if status != http.StatusForbidden {
+			t.Fatalf("expected %s %s to be forbidden, got %d", request.method, request.path, status)
+		}
+	}
+
+	createRecordAs(h, namespaceAdmin, assignedID)
+
+	var namespaces struct {
+		Items []namespaceResponse `json:"items"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/namespaces", namespaceAdmin, nil, &namespaces)
+
+	if len(namespaces.Items) != 1 || namespaces.Items[0].ID != assignedID {
+		t.Fatalf("expected only the assigned namespace to be listed, got %+v", namespaces.Items)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, fmt.Sprintf("/api/v1/admins/%s/roles", auditorID), h.AdminToken(), map[string]any{
+		"roles": []map[string]any{{"role": "namespace-admin"}},
+	}, nil)
+
+	createRecordAs(h, auditor, otherID)
+
+	var current struct {
+		ID string `json:"id"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", h.AdminToken(), nil, &current)
+
+	status := h.Do(http.MethodPut, fmt.Sprintf("/api/v1/admins/%s/roles", current.ID), h.AdminToken(), map[string]any{
+		"roles": []map[string]any{{"role": "auditor"}},
+	}, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected the last superadmin to keep the role, got %d", status)
+	}
+
+	status = h.Do(http.MethodPut, fmt.Sprintf("/api/v1/admins/%s/roles", auditorID), h.AdminToken(), map[string]any{
+		"roles": []map[string]any{{"role": "superadmin", "namespace_id": assignedID}},
+	}, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected superadmins limited to a namespace to be rejected, got %d", status)
+	}
+}
+
+// addAdmin adds an admin with the roles and returns its ID and a bearer
+// authorization header for it.
+func addAdmin(h *qyrodnstest.Harness, username string, roles ...map[string]any) (string, string) {
+	var added struct {
+		Admin struct {
+			ID string `json:"id"`
+		} `json:"admin"`
+		Password string `json:"password"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/api/v1/admins", h.AdminToken(), map[string]any{"username": username, "roles": roles}, &added)
+
+	return added.Admin.ID, qyrodnstest.Bearer(login(h, username, added.Password).Token)
+}
+
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func login(h *qyrodnstest.Harness, username string, password string) tokenResponse {
+	var token tokenResponse
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/api/v1/admins/token", "", map[string]any{"username": username, "password": password}, &token)
+
+	return token
+}
+
+func createRecordAs(h *qyrodnstest.Harness, authorization string, namespaceID string) {
+	h.MustDo(http.StatusCreated, http.MethodPost, fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID), authorization, map[string]any{
+		"name":  "example.com",
+		"type":  "A",
+		"value": "192.168.0.105",
+		"ttl":   60,
+		"class": "IN",
+	}, nil)
+}
+
+func TestAdminTokens(t *testing.T) {
+	h := qyrodnstest.New(t)
+	h.AdminToken()
+
+	first := login(h, qyrodnstest.AdminUsername, qyrodnstest.AdminPassword)
+	second := login(h, qyrodnstest.AdminUsername, qyrodnstest.AdminPassword)
+
+	var refreshed tokenResponse
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/api/v1/admins/token/refresh", "", map[string]any{"refresh_token": first.RefreshToken}, &refreshed)
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", qyrodnstest.Bearer(refreshed.Token), nil, nil)
+
+	status := h.Do(http.MethodPost, "/api/v1/admins/token/refresh", "", map[string]any{"refresh_token": first.RefreshToken}, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected a used refresh token to be rejected, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/api/v1/admins/logout", qyrodnstest.Bearer(refreshed.Token), nil, nil)
+
+	for _, token := range []string{first.Token, refreshed.Token} {
+		status := h.Do(http.MethodGet, "/api/v1/admins/current", qyrodnstest.Bearer(token), nil, nil)
+
+		if status != http.StatusUnauthorized {
+			t.Fatalf("expected the tokens of the ended session to be revoked, got %d", status)
+		}
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", qyrodnstest.Bearer(second.Token), nil, nil)
+
+	// Tokens must be issued by and for this server.
+	claims := jwt.MapClaims{}
+
+	_, _, err := jwt.NewParser().ParseUnverified(second.Token, claims)
+
+	if err != nil {
+		t.Fatalf("error parsing token: %v", err)
+	}
+
+	for claim, value := range map[string]string{"iss": "other", "aud": "other"} {
+		forged := jwt.MapClaims{}
+
+		for k, v := range claims {
+			forged[k] = v
+		}
+
+		forged[claim] = value
+
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, forged).SignedString([]byte("secret"))
+
+		if err != nil {
+			t.Fatalf("error signing token: %v", err)
+		}
+
+		status := h.Do(http.MethodGet, "/api/v1/admins/current", qyrodnstest.Bearer(token), nil, nil)
+
+		if status != http.StatusUnauthorized {
+			t.Fatalf("expected a token with another %s to be rejected, got %d", claim, status)
+		}
+	}
+
+	adminID, token := addAdmin(h, "deleted", map[string]any{"role": "auditor"})
+
+	h.MustDo(http.StatusOK, http.MethodDelete, fmt.Sprintf("/api/v1/admins/%s", adminID), h.AdminToken(), nil, nil)
+
+	status = h.Do(http.MethodGet, "/api/v1/admins/current", token, nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected the tokens of a deleted admin to be revoked, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, "/api/v1/admins/current/password", qyrodnstest.Bearer(second.Token), map[string]any{"password": "changed"}, nil)
+
+	status = h.Do(http.MethodGet, "/api/v1/admins/current", qyrodnstest.Bearer(second.Token), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected changing the password to revoke tokens, got %d", status)
+	}
+
+	status = h.Do(http.MethodPost, "/api/v1/admins/token/refresh", "", map[string]any{"refresh_token": second.RefreshToken}, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected changing the password to revoke refresh tokens, got %d", status)
+	}
+}
+
+func TestAsymmetricAdminTokens(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			h := qyrodnstest.New(t, &qyrodns.ServerConfig{
+				JwtSigningKey: "secret",
+				JwtIssuer:     "qyrodns",
+				JwtAudience:   "qyrodns",
+				JwtAlgorithm:  algorithm,
+			})
+
+			h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", h.AdminToken(), nil, nil)
+
+			token := strings.TrimPrefix(h.AdminToken(), "Bearer ")
+			claims := jwt.MapClaims{}
+
+			// The token must verify with the published key its kid names.
+			keyFunc := func(token *jwt.Token) (interface{}, error) {
+				return jwkPublicKey(t, h, token.Header["kid"].(string)), nil
+			}
+
+			parsed, err := jwt.ParseWithClaims(token, claims, keyFunc)
+
+			if err != nil || parsed.Method.Alg() != algorithm {
+				t.Fatalf("expected the token to be signed with a published %s key, got %v", algorithm, err)
+			}
+
+			// HMAC tokens are no longer accepted, even signed with the secret.
+			forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+
+			if err != nil {
+				t.Fatalf("error signing token: %v", err)
+			}
+
+			status := h.Do(http.MethodGet, "/api/v1/admins/current", qyrodnstest.Bearer(forged), nil, nil)
+
+			if status != http.StatusUnauthorized {
+				t.Fatalf("expected an HS256 token to be rejected, got %d", status)
+			}
+		})
+	}
+}
+
+func TestAdminTokenSigningKeyRotation(t *testing.T) {
+	h := qyrodnstest.New(t, &qyrodns.ServerConfig{
+		JwtIssuer:              "qyrodns",
+		JwtAudience:            "qyrodns",
+		JwtAlgorithm:           "ES256",
+		JwtKeyRotationInterval: time.Second,
+	})
+
+	first := h.AdminToken()
+	firstKid := tokenKid(t, first)
+
+	var rotated string
+
+	for range 50 {
+		rotated = qyrodnstest.Bearer(login(h, qyrodnstest.AdminUsername, qyrodnstest.AdminPassword).Token)
+
+		if tokenKid(t, rotated) != firstKid {
+			break
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if tokenKid(t, rotated) == firstKid {
+		t.Fatalf("expected the signing key to be rotated")
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", rotated, nil, nil)
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/admins/current", first, nil, nil)
+
+	jwkPublicKey(t, h, firstKid)
+}
+
+func tokenKid(t *testing.T, authorization string) string {
+	t.Helper()
+
+	token, _, err := jwt.NewParser().ParseUnverified(strings.TrimPrefix(authorization, "Bearer "), jwt.MapClaims{})
+
+	if err != nil {
+		t.Fatalf("error parsing token: %v", err)
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	return kid
+}
+
+// jwkPublicKey returns the public key with the kid published in the JWKS.
+func jwkPublicKey(t *testing.T, h *qyrodnstest.Harness, kid string) any {
+	t.Helper()
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/.well-known/jwks.json", "", nil, &jwks)
+
+	decode := func(value string) []byte {
+		decoded, err := base64.RawURLEncoding.DecodeString(value)
+
+		if err != nil {
+			t.Fatalf("error decoding %s: %v", value, err)
+		}
+
+		return decoded
+	}
+
+	for _, jwk := range jwks.Keys {
+		if jwk["kid"] != kid {
+			continue
+		}
+
+		switch jwk["kty"] {
+		case "RSA":
+			return &rsa.PublicKey{N: new(big.Int).SetBytes(decode(jwk["n"])), E: int(new(big.Int).SetBytes(decode(jwk["e"])).Int64())}
+		case "EC":
+			return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(decode(jwk["x"])), Y: new(big.Int).SetBytes(decode(jwk["y"]))}
+		case "OKP":
+			return ed25519.PublicKey(decode(jwk["x"]))
+		}
+	}
+
+	t.Fatalf("expected key %s to be published, got %+v", kid, jwks.Keys)
+
+	return nil
+}
+
+func TestNamespaceTrashAndRestore(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+
+	h.MustDo(http.StatusOK, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s", namespaceID), h.AdminToken(), nil, nil)
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN after namespace deletion, got %v", response)
+	}
+
+	var trash struct {
+		Items []namespaceResponse `json:"items"`
+		Total int64               `json:"total"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/namespaces/trash", h.AdminToken(), nil, &trash)
+
+	if len(trash.Items) != 1 || trash.Total != 1 || trash.Items[0].ID != namespaceID {
+		t.Fatalf("expected the namespace in the trash, got %+v", trash)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/restore", namespaceID), h.AdminToken(), nil, nil)
+
+	response = h.Query("example.com", dns.TypeA)
+
+	if len(response.Answer) != 1 {
+		t.Fatalf("expected the restored namespace to be served, got %v", response)
+	}
+}
+
+func TestRecordTrashAndRestore(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	recordID := createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+	recordsPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID)
+
+	h.MustDo(http.StatusOK, http.MethodDelete, fmt.Sprintf("%s/%s", recordsPath, recordID), h.AdminToken(), nil, nil)
+
+	var trash recordPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"/trash", h.AdminToken(), nil, &trash)
+
+	if len(trash.Items) != 1 || trash.Total != 1 || trash.Items[0].ID != recordID {
+		t.Fatalf("expected the record in the trash, got %+v", trash)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("%s/%s/restore", recordsPath, recordID), h.AdminToken(), nil, nil)
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if len(response.Answer) != 1 {
+		t.Fatalf("expected the restored record to be served, got %v", response)
+	}
+
+	status := h.Do(http.MethodPost, fmt.Sprintf("%s/%s/restore", recordsPath, recordID), h.AdminToken(), nil, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected restoring a live record to fail, got %d", status)
+	}
+}
+
+func TestTrashIsPurged(t *testing.T) {
+	h := qyrodnstest.New(t, &qyrodns.ServerConfig{
+		JwtSigningKey:      "secret",
+		JwtIssuer:          "qyrodns",
+		JwtAudience:        "qyrodns",
+		TrashRetention:     time.Nanosecond,
+		TrashPurgeInterval: 10 * time.Millisecond,
+	})
+
+	namespaceID := createNamespace(h, "example")
+	createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+
+	h.MustDo(http.StatusOK, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s", namespaceID), h.AdminToken(), nil, nil)
+
+	status := http.StatusOK
+
+	for range 100 {
+		status = h.Do(http.MethodGet, fmt.Sprintf("/api/v1/namespaces/%s", namespaceID), h.AdminToken(), nil, nil)
+
+		if status != http.StatusOK {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected the purged namespace to be gone, got %d", status)
+	}
+}
+
+func TestRecordHistoryAndRollback(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	recordID := createRecord(h, namespaceID, "example.com", "A", "192.168.0.105")
+	recordsPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID)
+
+	time.Sleep(10 * time.Millisecond)
+	checkpoint := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	h.MustDo(http.StatusOK, http.MethodPut, fmt.Sprintf("%s/%s", recordsPath, recordID), h.AdminToken(), map[string]any{"value": "10.0.0.1"}, nil)
+	createRecord(h, namespaceID, "www.example.com", "A", "10.0.0.2")
+
+	var history revisionPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, fmt.Sprintf("%s/%s/history", recordsPath, recordID), h.AdminToken(), nil, &history)
+
+	if len(history.Items) != 2 || history.Items[0].Action != "update" || history.Items[0].Version != 2 {
+		t.Fatalf("expected the update on top of the creation, got %+v", history)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/api/v1/namespaces/%s/rollback", namespaceID), h.AdminToken(), map[string]any{"timestamp": checkpoint}, nil)
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if len(response.Answer) != 1 || response.Answer[0].(*dns.A).A.String() != "192.168.0.105" {
+		t.Fatalf("expected the value from before the update, got %v", response)
+	}
+
+	response = h.Query("www.example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected the record created after the checkpoint to be gone, got %v", response)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"/history?size=3", h.AdminToken(), nil, &history)
+
+	if len(history.Items) != 3 || history.Total != 5 || history.Items[0].Action != "rollback" || history.NextCursor == "" {
+		t.Fatalf("expected the rollback on the first page of the namespace history, got %+v", history)
+	}
+
+	var last revisionPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"/history?size=3&cursor="+history.NextCursor, h.AdminToken(), nil, &last)
+
+	if len(last.Items) != 2 || last.Items[1].Action != "create" || last.NextCursor != "" {
+		t.Fatalf("expected the first changes on the last page of the namespace history, got %+v", last)
+	}
+
+	status := h.Do(http.MethodGet, recordsPath+"/history?size=1000", h.AdminToken(), nil, nil)
+
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected a page size over the maximum to be rejected, got %d", status)
+	}
+}
+
+func TestRecordChanges(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	recordID := createRecord(h, namespaceID, "www.example.com", "A", "192.168.0.105")
+	createRecord(h, namespaceID, "example.com", "SOA", "ns1.example.com. admin.example.com. 1 7200 3600 1209600 300")
+	changesPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records/changes", namespaceID)
+
+	var changes struct {
+		Serial  uint32           `json:"serial"`
+		Records []recordResponse `json:"records"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, changesPath, h.AdminToken(), map[string]any{
+		"changes": []map[string]any{
+			{"action": "delete", "record_id": recordID},
+			{"action": "create", "name": "www.example.com", "type": "CNAME", "value": "example.github.io", "ttl": 60, "class": "IN"},
+		},
+	}, &changes)
+
+	if changes.Serial != 3 || len(changes.Records) != 2 {
+		t.Fatalf("expected one serial bump for the changeset, got %+v", changes)
+	}
+
+	response := h.Query("www.example.com", dns.TypeCNAME)
+
+	if len(response.Answer) != 1 {
+		t.Fatalf("expected the CNAME to be served, got %v", response)
+	}
+
+	response = h.Query("example.com", dns.TypeSOA)
+
+	if len(response.Answer) != 1 || response.Answer[0].(*dns.SOA).Serial != 3 {
+		t.Fatalf("expected the namespace serial in the SOA record, got %v", response)
+	}
+
+	var failure struct {
+		Errors []struct {
+			Code string `json:"code"`
+		} `json:"errors"`
+	}
+
+	status := h.Do(http.MethodPost, changesPath, h.AdminToken(), map[string]any{
+		"changes": []map[string]any{
+			{"action": "create", "name": "www.example.com", "type": "A", "value": "10.0.0.1", "ttl": 60, "class": "IN"},
+			{"action": "update", "record_id": recordID, "value": "10.0.0.2"},
+		},
+	}, &failure)
+
+	if status != http.StatusBadRequest || len(failure.Errors) != 2 {
+		t.Fatalf("expected both problems to be reported, got %d %+v", status, failure)
+	}
+
+	response = h.Query("www.example.com", dns.TypeA)
+
+	if len(response.Answer) != 0 {
+		t.Fatalf("expected nothing of the invalid changeset to be applied, got %v", response)
+	}
+}
+
+func TestSerialNeverGoesBack(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	soaID := createRecord(h, namespaceID, "example.com", "SOA", "ns1.example.com. admin.example.com. 2025101901 7200 3600 1209600 300")
+
+	serial := func() uint32 {
+		response := h.Query("example.com", dns.TypeSOA)
+
+		if len(response.Answer) != 1 {
+			t.Fatalf("expected the SOA record to be served, got %v", response)
+		}
+
+		return response.Answer[0].(*dns.SOA).Serial
+	}
+
+	if served := serial(); served != 2025101902 {
+		t.Fatalf("expected the serial to go on from the date-based one, got %d", served)
+	}
+
+	createRecord(h, namespaceID, "www.example.com", "A", "192.168.0.105")
+
+	if served := serial(); served != 2025101903 {
+		t.Fatalf("expected the serial to be bumped, got %d", served)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, fmt.Sprintf("/admin/api/v1/namespaces/%s/records/%s", namespaceID, soaID), h.AdminToken(), map[string]any{
+		"value": "ns1.example.com. admin.example.com. 2025102001 7200 3600 1209600 300",
+	}, nil)
+
+	if served := serial(); served != 2025102002 {
+		t.Fatalf("expected the serial to go on from the one set by hand, got %d", served)
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	recordID := createRecord(h, namespaceID, "example.com", "A", "192.0.2.1")
+	recordPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records/%s", namespaceID, recordID)
+
+	header := http.Header{}
+	header.Set("Authorization", h.AdminToken())
+
+	status, responseHeader := h.DoWithHeader(http.MethodGet, recordPath, header, nil, nil)
+
+	if status != http.StatusOK || responseHeader.Get("ETag") != `"1"` {
+		t.Fatalf("expected 200 with ETag \"1\", got %d with %q", status, responseHeader.Get("ETag"))
+	}
+
+	header.Set("If-Match", `"1"`)
+
+	status, responseHeader = h.DoWithHeader(http.MethodPut, recordPath, header, map[string]any{"value": "10.0.0.1"}, nil)
+
+	if status != http.StatusOK || responseHeader.Get("ETag") != `"2"` {
+		t.Fatalf("expected 200 with ETag \"2\", got %d with %q", status, responseHeader.Get("ETag"))
+	}
+
+	status, _ = h.DoWithHeader(http.MethodPut, recordPath, header, map[string]any{"value": "10.0.0.2"}, nil)
+
+	if status != http.StatusPreconditionFailed {
+		t.Fatalf("expected stale update to fail with 412, got %d", status)
+	}
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if len(response.Answer) != 1 || response.Answer[0].(*dns.A).A.String() != "10.0.0.1" {
+		t.Fatalf("expected the first update to be kept, got %v", response.Answer)
+	}
+
+	status, _ = h.DoWithHeader(http.MethodPut, fmt.Sprintf("/api/v1/namespaces/%s", namespaceID), header, map[string]any{"name": "renamed"}, nil)
+
+	if status != http.StatusOK {
+		t.Fatalf("expected namespace update at version 1 to succeed, got %d", status)
+	}
+
+	status, _ = h.DoWithHeader(http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s", namespaceID), header, nil, nil)
+
+	if status != http.StatusPreconditionFailed {
+		t.Fatalf("expected stale namespace deletion to fail with 412, got %d", status)
+	}
+}
+
+func TestRRsets(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	keptID := createRecord(h, namespaceID, "example.com", "A", "192.0.2.1")
+	createRecord(h, namespaceID, "example.com", "A", "192.0.2.2")
+	rrsetPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/rrsets/example.com/a", namespaceID)
+
+	var rrset struct {
+		TTL     uint32           `json:"ttl"`
+		Records []recordResponse `json:"records"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, rrsetPath, h.AdminToken(), map[string]any{
+		"values": []string{"192.0.2.1", "192.0.2.3"},
+		"ttl":    300,
+		"class":  "IN",
+	}, &rrset)
+
+	if rrset.TTL != 300 || len(rrset.Records) != 2 || rrset.Records[0].ID != keptID {
+		t.Fatalf("unexpected replaced rrset %+v", rrset)
+	}
+
+	response := h.Query("example.com", dns.TypeA)
+
+	if len(response.Answer) != 2 {
+		t.Fatalf("expected 2 answers, got %v", response)
+	}
+
+	for _, answer := range response.Answer {
+		if answer.Header().Ttl != 300 || answer.(*dns.A).A.String() == "192.0.2.2" {
+			t.Fatalf("unexpected answer %v", answer)
+		}
+	}
+
+	h.MustDo(http.StatusOK, http.MethodDelete, rrsetPath, h.AdminToken(), nil, nil)
+
+	response = h.Query("example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected NXDOMAIN after deleting the rrset, got %v", response)
+	}
+
+	status := h.Do(http.MethodGet, rrsetPath, h.AdminToken(), nil, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected deleted rrset to be missing, got %d", status)
+	}
+}
+
+func TestRecordSync(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	createRecord(h, namespaceID, "example.com", "A", "192.0.2.1")
+	createRecord(h, namespaceID, "old.example.com", "A", "192.0.2.2")
+	syncPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records/sync", namespaceID)
+
+	desired := []map[string]any{
+		{"name": "example.com", "type": "A", "value": "192.0.2.1", "ttl": 60, "class": "IN"},
+		{"name": "www.example.com", "type": "CNAME", "value": "example.com", "ttl": 60, "class": "IN"},
+	}
+
+	var plan struct {
+		Serial  uint32 `json:"serial"`
+		Changes []struct {
+			Action string `json:"action"`
+			Name   string `json:"name"`
+		} `json:"changes"`
+		Unchanged int `json:"unchanged"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, syncPath, h.AdminToken(), map[string]any{"records": desired, "dry_run": true}, &plan)
+
+	if len(plan.Changes) != 2 || plan.Unchanged != 1 || plan.Serial != 0 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	response := h.Query("old.example.com", dns.TypeA)
+
+	if len(response.Answer) != 1 {
+		t.Fatalf("expected dry run to change nothing, got %v", response)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, syncPath, h.AdminToken(), map[string]any{"records": desired}, &plan)
+
+	if plan.Serial == 0 {
+		t.Fatalf("expected applied plan to bump the serial, got %+v", plan)
+	}
+
+	response = h.Query("old.example.com", dns.TypeA)
+
+	if response.Rcode != dns.RcodeNameError {
+		t.Fatalf("expected undesired record to be deleted, got %v", response)
+	}
+
+	response = h.Query("www.example.com", dns.TypeCNAME)
+
+	if len(response.Answer) != 1 {
+		t.Fatalf("expected desired record to be created, got %v", response)
+	}
+}
+
+func TestRecordValidation(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	recordsPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID)
+
+	var failure struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	}
+
+	status := h.Do(http.MethodPost, recordsPath, h.AdminToken(), map[string]any{
+		"name":  "example.com",
+		"type":  "AAAA",
+		"value": "192.0.2.1",
+		"ttl":   60,
+		"class": "IN",
+	}, &failure)
+
+	if status != http.StatusBadRequest || len(failure.Errors) != 1 || failure.Errors[0].Code != "invalid_value" {
+		t.Fatalf("expected an invalid value, got %d %+v", status, failure)
+	}
+
+	createRecord(h, namespaceID, "example.com", "SOA", "ns1.example.com. admin.example.com. 1 7200 3600 1209600 300")
+	recordID := createRecord(h, namespaceID, "www.example.com", "A", "192.0.2.1")
+
+	status = h.Do(http.MethodPost, recordsPath, h.AdminToken(), map[string]any{
+		"name":  "www.example.com",
+		"type":  "A",
+		"value": "192.0.2.2",
+		"ttl":   300,
+		"class": "IN",
+	}, &failure)
+
+	if status != http.StatusBadRequest || len(failure.Errors) != 1 || failure.Errors[0].Code != "ttl_mismatch" {
+		t.Fatalf("expected a TTL differing from the rrset, got %d %+v", status, failure)
+	}
+
+	createRecord(h, namespaceID, "www.example.com", "A", "192.0.2.2")
+
+	status = h.Do(http.MethodPut, recordsPath+"/"+recordID, h.AdminToken(), map[string]any{"ttl": 300}, &failure)
+
+	if status != http.StatusBadRequest || len(failure.Errors) != 1 || failure.Errors[0].Code != "ttl_mismatch" {
+		t.Fatalf("expected a TTL update differing from the rrset, got %d %+v", status, failure)
+	}
+
+	status = h.Do(http.MethodPost, recordsPath, h.AdminToken(), map[string]any{
+		"name":  "www.example.org",
+		"type":  "A",
+		"value": "192.0.2.1",
+		"ttl":   60,
+		"class": "IN",
+	}, &failure)
+
+	if status != http.StatusBadRequest || len(failure.Errors) != 1 || failure.Errors[0].Code != "out_of_zone" {
+		t.Fatalf("expected a name outside of the zone, got %d %+v", status, failure)
+	}
+
+	var lint struct {
+		Valid bool `json:"valid"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"/lint", h.AdminToken(), nil, &lint)
+
+	if !lint.Valid {
+		t.Fatalf("expected the namespace to be valid, got %+v", lint)
+	}
+}
+
+func TestRecordListFilters(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	createRecord(h, namespaceID, "b.example.com", "A", "192.0.2.1")
+	createRecord(h, namespaceID, "a.example.com", "A", "192.0.2.2")
+	createRecord(h, namespaceID, "a.example.com", "TXT", "hello")
+	createRecord(h, namespaceID, "example.org", "A", "192.0.2.3")
+	recordsPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID)
+
+	var records recordPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"?name=example.com&name_match=suffix&type=a&sort=name", h.AdminToken(), nil, &records)
+
+	if len(records.Items) != 2 || records.Items[0].Name != "a.example.com" || records.Items[1].Name != "b.example.com" {
+		t.Fatalf("unexpected filtered records %+v", records)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"?name=A.EXAMPLE.COM.", h.AdminToken(), nil, &records)
+
+	if len(records.Items) != 2 {
+		t.Fatalf("expected exact names to match case-insensitively, got %+v", records)
+	}
+
+	status := h.Do(http.MethodGet, recordsPath+"?sort=value", h.AdminToken(), nil, nil)
+
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected an invalid sort to be rejected, got %d", status)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+	names := []string{"c.example.com", "a.example.com", "d.example.com", "b.example.com", "e.example.com"}
+
+	for _, name := range names {
+		createRecord(h, namespaceID, name, "A", "192.0.2.1")
+	}
+
+	recordsPath := fmt.Sprintf("/admin/api/v1/namespaces/%s/records", namespaceID)
+	listed := make([]string, 0)
+	cursor := ""
+
+	for range names {
+		var page recordPage
+
+		h.MustDo(http.StatusOK, http.MethodGet, recordsPath+"?sort=-name&size=2&cursor="+cursor, h.AdminToken(), nil, &page)
+
+		if page.Total != int64(len(names)) {
+			t.Fatalf("expected a total of %d, got %d", len(names), page.Total)
+		}
+
+		for _, record := range page.Items {
+			listed = append(listed, record.Name)
+		}
+
+		cursor = page.NextCursor
+
+		if cursor == "" {
+			break
+		}
+	}
+
+	expected := []string{"e.example.com", "d.example.com", "c.example.com", "b.example.com", "a.example.com"}
+
+	if fmt.Sprint(listed) != fmt.Sprint(expected) {
+		t.Fatalf("expected pages to list %v, got %v", expected, listed)
+	}
+
+	for _, query := range []string{"?size=0", "?size=501", "?cursor=invalid"} {
+		status := h.Do(http.MethodGet, recordsPath+query, h.AdminToken(), nil, nil)
+
+		if status != http.StatusBadRequest {
+			t.Fatalf("expected %s to be rejected, got %d", query, status)
+		}
+	}
+}
+
+func TestRecordSearch(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	firstID := createNamespace(h, "first")
+	secondID := createNamespace(h, "second")
+	createRecord(h, firstID, "api.example.com", "A", "192.0.2.1")
+	createRecord(h, secondID, "www.example.org", "A", "192.0.2.1")
+	createRecord(h, secondID, "api.example.com", "TXT", "192.0.2.1")
+	createRecord(h, secondID, "mail.example.org", "A", "192.0.2.10")
+
+	var results struct {
+		Items []struct {
+			Record    recordResponse    `json:"record"`
+			Namespace namespaceResponse `json:"namespace"`
+		} `json:"items"`
+		Total int64 `json:"total"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/admin/api/v1/records?value=192.0.2.1&type=a", h.AdminToken(), nil, &results)
+
+	if results.Total != 2 || results.Items[0].Namespace.Name != "first" || results.Items[1].Namespace.Name != "second" {
+		t.Fatalf("expected the A records of 192.0.2.1 in both namespaces, got %+v", results)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/admin/api/v1/records?name=API.example.com", h.AdminToken(), nil, &results)
+
+	if results.Total != 2 || results.Items[0].Namespace.ID != firstID || results.Items[1].Namespace.ID != secondID {
+		t.Fatalf("expected api.example.com in both namespaces, got %+v", results)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/admin/api/v1/records?value=192.0.2.&value_match=prefix&name=example.org&name_match=suffix", h.AdminToken(), nil, &results)
+
+	if results.Total != 2 {
+		t.Fatalf("expected the records under example.org, got %+v", results)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodDelete, fmt.Sprintf("/api/v1/namespaces/%s", secondID), h.AdminToken(), nil, nil)
+	h.MustDo(http.StatusOK, http.MethodGet, "/admin/api/v1/records?value=192.0.2.1", h.AdminToken(), nil, &results)
+
+	if results.Total != 1 || len(results.Items) != 1 || results.Items[0].Namespace.ID != firstID {
+		t.Fatalf("expected the records of the trashed namespace to be left out, got %+v", results)
+	}
+
+	status := h.Do(http.MethodGet, "/admin/api/v1/records", qyrodnstest.ApiKey("invalid"), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected the search to be reserved to admins, got %d", status)
+	}
+}
+
+func TestRecordValueReplacement(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	firstID := createNamespace(h, "first")
+	secondID := createNamespace(h, "second")
+	createRecord(h, firstID, "api.example.com", "A", "192.0.2.1")
+	createRecord(h, firstID, "www.example.com", "A", "192.0.2.1")
+	createRecord(h, secondID, "www.example.org", "A", "192.0.2.1")
+	createRecord(h, secondID, "note.example.org", "TXT", "192.0.2.1")
+
+	type replacementResponse struct {
+		DryRun     bool `json:"dry_run"`
+		Replaced   int  `json:"replaced"`
+		Namespaces []struct {
+			NamespaceID string `json:"namespace_id"`
+			Serial      uint32 `json:"serial"`
+		} `json:"namespaces"`
+	}
+
+	var preview replacementResponse
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/admin/api/v1/records/replace", h.AdminToken(), map[string]any{
+		"value":       "192.0.2.1",
+		"type":        "A",
+		"replacement": "198.51.100.1",
+		"dry_run":     true,
+	}, &preview)
+
+	if !preview.DryRun || preview.Replaced != 3 || len(preview.Namespaces) != 2 || preview.Namespaces[0].NamespaceID != firstID {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+
+	var search recordPage
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/admin/api/v1/records?value=198.51.100.1", h.AdminToken(), nil, &search)
+
+	if search.Total != 0 {
+		t.Fatalf("expected a dry run to leave records unchanged, got %+v", search)
+	}
+
+	status := h.Do(http.MethodPost, "/admin/api/v1/records/replace", h.AdminToken(), map[string]any{
+		"value":       "192.0.2.1",
+		"type":        "A",
+		"replacement": "not-an-address",
+	}, nil)
+
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected an invalid replacement to be rejected, got %d", status)
+	}
+
+	var replaced replacementResponse
+
+	h.MustDo(http.StatusOK, http.MethodPost, "/admin/api/v1/records/replace", h.AdminToken(), map[string]any{
+		"value":         "192.0.2.1",
+		"type":          "A",
+		"namespace_ids": []string{secondID},
+		"replacement":   "198.51.100.1",
+	}, &replaced)
+
+	if replaced.Replaced != 1 || len(replaced.Namespaces) != 1 || replaced.Namespaces[0].Serial == 0 {
+		t.Fatalf("expected the record of the second namespace to be replaced, got %+v", replaced)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/admin/api/v1/records?value=192.0.2.1", h.AdminToken(), nil, &search)
+
+	if search.Total != 3 {
+		t.Fatalf("expected the other records to keep their value, got %+v", search)
+	}
+}
+
+func TestApiKeySecrets(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{"name": "ci"}, &created)
+
+	if !strings.HasPrefix(created.Secret, fmt.Sprintf("qdns_%s_", created.ID)) {
+		t.Fatalf("expected the secret to carry the api key ID, got %s", created.Secret)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+		"api_key_id": created.ID,
+		"actions":    []string{"read"},
+	}, nil)
+
+	recordsPath := fmt.Sprintf("/api/v1/namespaces/%s/records", namespaceID)
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	var fetched map[string]any
+
+	h.MustDo(http.StatusOK, http.MethodGet, fmt.Sprintf("/api/v1/api-keys/%s", created.ID), h.AdminToken(), nil, &fetched)
+
+	if _, ok := fetched["secret"]; ok {
+		t.Fatalf("expected the secret not to be shown again, got %+v", fetched)
+	}
+
+	status := h.Do(http.MethodGet, fmt.Sprintf("/api/v1/api-keys/%s/secret", created.ID), h.AdminToken(), nil, nil)
+
+	if status != http.StatusNotFound {
+		t.Fatalf("expected the secret not to be retrievable, got %d", status)
+	}
+
+	forged := created.Secret[:len(created.Secret)-1] + "x"
+
+	if forged == created.Secret {
+		forged = created.Secret[:len(created.Secret)-1] + "y"
+	}
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey(forged), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong secret to be rejected, got %d", status)
+	}
+
+	var reset struct {
+		Secret string `json:"secret"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, fmt.Sprintf("/api/v1/api-keys/%s/secret", created.ID), h.AdminToken(), nil, &reset)
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected the old secret to be rejected after a reset, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(reset.Secret), nil, nil)
+}
+
+func TestLegacyApiKeySecretsAreHashed(t *testing.T) {
+	ctx := context.Background()
+	db := embedded.NewMemoryDB()
+	id := primitive.NewObjectID()
+	legacySecret := "legacySecretGeneratedBeforeSecretsWereHashed"
+
+	err := embedded.NewCollection[bson.M](db, "api_keys").Put(ctx, id.Hex(), &bson.M{"_id": id, "name": "legacy", "secret": legacySecret})
+
+	if err != nil {
+		t.Fatalf("error seeding api key: %v", err)
+	}
+
+	store := apikey.NewEmbeddedStore(db)
+
+	count, err := store.HashLegacySecrets(ctx)
+
+	if err != nil || count != 1 {
+		t.Fatalf("expected one secret to be hashed, got %d, %v", count, err)
+	}
+
+	stored, err := embedded.NewCollection[bson.M](db, "api_keys").Get(ctx, id.Hex())
+
+	if err != nil {
+		t.Fatalf("error reading api key: %v", err)
+	}
+
+	if _, ok := (*stored)["secret"]; ok {
+		t.Fatalf("expected the plaintext secret to be gone, got %+v", *stored)
+	}
+
+	authenticator := auth.NewAuthenticator(auth.NewHMACKeys("secret"), "qyrodns", "qyrodns", time.Hour, store)
+
+	for presented, valid := range map[string]bool{legacySecret: true, legacySecret + "x": false} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("Authorization", qyrodnstest.ApiKey(presented))
+
+		authenticated, err := authenticator.ValidateApiKeyContext(c, ctx)
+
+		if valid && (err != nil || authenticated.ID != id.Hex()) {
+			t.Fatalf("expected the legacy secret to be accepted, got %v", err)
+		}
+
+		if !valid && err == nil {
+			t.Fatalf("expected %s to be rejected", presented)
+		}
+	}
+}
+
+func TestApiKeySecretRotation(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{"name": "ci"}, &created)
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+		"api_key_id": created.ID,
+		"actions":    []string{"read"},
+	}, nil)
+
+	recordsPath := fmt.Sprintf("/api/v1/namespaces/%s/records", namespaceID)
+	secretPath := fmt.Sprintf("/api/v1/api-keys/%s/secret", created.ID)
+
+	var rotated struct {
+		Secret                  string     `json:"secret"`
+		PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, secretPath, h.AdminToken(), map[string]any{"overlap": "1h"}, &rotated)
+
+	if rotated.PreviousSecretExpiresAt == nil || time.Until(*rotated.PreviousSecretExpiresAt) <= 59*time.Minute {
+		t.Fatalf("expected the previous secret to expire in an hour, got %v", rotated.PreviousSecretExpiresAt)
+	}
+
+	for _, presented := range []string{created.Secret, rotated.Secret} {
+		h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(presented), nil, nil)
+	}
+
+	status := h.Do(http.MethodPut, secretPath, h.AdminToken(), map[string]any{"overlap": "soon"}, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected an invalid overlap to be rejected, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodDelete, secretPath+"/previous", h.AdminToken(), nil, nil)
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected the previous secret to be rejected once the overlap ended, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(rotated.Secret), nil, nil)
+
+	status = h.Do(http.MethodDelete, secretPath+"/previous", h.AdminToken(), nil, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected ending a finished overlap to fail, got %d", status)
+	}
+
+	var reset struct {
+		Secret                  string     `json:"secret"`
+		PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, secretPath, h.AdminToken(), nil, &reset)
+
+	if reset.PreviousSecretExpiresAt != nil {
+		t.Fatalf("expected no overlap by default, got %v", reset.PreviousSecretExpiresAt)
+	}
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey(rotated.Secret), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected the previous secret to be rejected without an overlap, got %d", status)
+	}
+}
+
+func TestApiKeyAllowedCIDRs(t *testing.T) {
+	h := qyrodnstest.New(t, &qyrodns.ServerConfig{
+		JwtSigningKey:  "secret",
+		JwtIssuer:      "qyrodns",
+		JwtAudience:    "qyrodns",
+		TrustedProxies: []string{"127.0.0.1"},
+	})
+
+	namespaceID := createNamespace(h, "example")
+
+	status := h.Do(http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{
+		"name":          "ci",
+		"allowed_cidrs": []string{"runners"},
+	}, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected an invalid cidr to be rejected, got %d", status)
+	}
+
+	var apiKey struct {
+		ID           string   `json:"id"`
+		Secret       string   `json:"secret"`
+		AllowedCIDRs []string `json:"allowed_cidrs"`
+	}
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{
+		"name":          "ci",
+		"allowed_cidrs": []string{"10.1.2.3/8", "2001:db8::1"},
+	}, &apiKey)
+
+	if !slices.Equal(apiKey.AllowedCIDRs, []string{"10.0.0.0/8", "2001:db8::1/128"}) {
+		t.Fatalf("expected the cidrs to be normalized, got %v", apiKey.AllowedCIDRs)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+		"api_key_id": apiKey.ID,
+		"actions":    []string{"read"},
+	}, nil)
+
+	recordsPath := fmt.Sprintf("/api/v1/namespaces/%s/records", namespaceID)
+
+	for forwardedFor, expectedStatus := range map[string]int{
+		"":                     http.StatusUnauthorized,
+		"192.168.0.1":          http.StatusUnauthorized,
+		"10.20.30.40":          http.StatusOK,
+		"10.20.30.40, 8.8.8.8": http.StatusUnauthorized,
+	} {
+		header := http.Header{}
+		header.Set("Authorization", qyrodnstest.ApiKey(apiKey.Secret))
+
+		if forwardedFor != "" {
+			header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		status, _ := h.DoWithHeader(http.MethodGet, recordsPath, header, nil, nil)
+
+		if status != expectedStatus {
+			t.Fatalf("expected %d when forwarded for %q, got %d", expectedStatus, forwardedFor, status)
+		}
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, fmt.Sprintf("/api/v1/api-keys/%s", apiKey.ID), h.AdminToken(), map[string]any{
+		"allowed_cidrs": []string{},
+	}, nil)
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(apiKey.Secret), nil, nil)
+}
+
+func TestApiKeyExpiryAndUsage(t *testing.T) {
+	h := qyrodnstest.New(t)
+
+	namespaceID := createNamespace(h, "example")
+
+	type apiKeyResponse struct {
+		ID         string     `json:"id"`
+		Secret     string     `json:"secret"`
+		ExpiresAt  *time.Time `json:"expires_at"`
+		Disabled   bool       `json:"disabled"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+		LastUsedIP string     `json:"last_used_ip"`
+	}
+
+	var created apiKeyResponse
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{
+		"name":       "ci",
+		"expires_at": time.Now().Add(time.Hour),
+	}, &created)
+
+	h.MustDo(http.StatusCreated, http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{"name": "unused"}, nil)
+
+	status := h.Do(http.MethodPost, "/api/v1/api-keys", h.AdminToken(), map[string]any{
+		"name":       "expired",
+		"expires_at": time.Now().Add(-time.Hour),
+	}, nil)
+
+	if status != http.StatusInternalServerError {
+		t.Fatalf("expected an expiry in the past to be rejected, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/api-keys", namespaceID), h.AdminToken(), map[string]any{
+		"api_key_id": created.ID,
+		"actions":    []string{"read"},
+	}, nil)
+
+	recordsPath := fmt.Sprintf("/api/v1/namespaces/%s/records", namespaceID)
+	apiKeyPath := fmt.Sprintf("/api/v1/api-keys/%s", created.ID)
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	var used apiKeyResponse
+
+	for range 100 {
+		h.MustDo(http.StatusOK, http.MethodGet, apiKeyPath, h.AdminToken(), nil, &used)
+
+		if used.LastUsedAt != nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if used.LastUsedAt == nil || used.LastUsedIP != "127.0.0.1" {
+		t.Fatalf("expected the use of the api key to be recorded, got %+v", used)
+	}
+
+	var listed struct {
+		Items []apiKeyResponse `json:"items"`
+		Total int64            `json:"total"`
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/api-keys?expiring_within=2h", h.AdminToken(), nil, &listed)
+
+	if listed.Total != 1 || listed.Items[0].ID != created.ID {
+		t.Fatalf("expected the expiring api key to be listed, got %+v", listed)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodGet, "/api/v1/api-keys?unused_for=1h", h.AdminToken(), nil, &listed)
+
+	if listed.Total != 0 {
+		t.Fatalf("expected recent api keys not to be stale, got %+v", listed)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, apiKeyPath, h.AdminToken(), map[string]any{"disabled": true}, nil)
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected a disabled api key to be rejected, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, apiKeyPath, h.AdminToken(), map[string]any{
+		"disabled":   false,
+		"expires_at": time.Now().Add(50 * time.Millisecond),
+	}, nil)
+
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	time.Sleep(100 * time.Millisecond)
+
+	status = h.Do(http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected an expired api key to be rejected, got %d", status)
+	}
+
+	h.MustDo(http.StatusOK, http.MethodPut, apiKeyPath, h.AdminToken(), map[string]any{"never_expires": true}, nil)
+	h.MustDo(http.StatusOK, http.MethodGet, recordsPath, qyrodnstest.ApiKey(created.Secret), nil, nil)
+}
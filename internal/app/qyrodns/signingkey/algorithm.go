@@ -0,0 +1,127 @@
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+)
+
+var methods = map[string]jwt.SigningMethod{
+	jwt.SigningMethodRS256.Alg(): jwt.SigningMethodRS256,
+	jwt.SigningMethodES256.Alg(): jwt.SigningMethodES256,
+	jwt.SigningMethodEdDSA.Alg(): jwt.SigningMethodEdDSA,
+}
+
+// Supported reports whether keys can be generated for the algorithm.
+func Supported(algorithm string) bool {
+	_, ok := methods[algorithm]
+
+	return ok
+}
+
+// generate returns a new private key for the algorithm, PEM-encoded in
+// PKCS #8.
+func generate(algorithm string) (string, error) {
+	var private crypto.Signer
+	var err error
+
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("signing algorithm %s is not supported", algorithm)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// parse decodes the private key of the key.
+func parse(key *Key) (*auth.Key, error) {
+	method, ok := methods[key.Algorithm]
+
+	if !ok {
+		return nil, fmt.Errorf("signing algorithm %s is not supported", key.Algorithm)
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key for signing key %s", key.ID)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := private.(crypto.Signer)
+
+	if !ok {
+		return nil, fmt.Errorf("invalid private key for signing key %s", key.ID)
+	}
+
+	return &auth.Key{ID: key.ID, Method: method, Private: signer, Public: signer.Public()}, nil
+}
+
+func newJWK(key *auth.Key) (*JWK, error) {
+	jwk := &JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+	switch public := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(public.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := public.ECDH()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The point is uncompressed: 0x04, then the coordinates.
+		coordinates := point.Bytes()[1:]
+
+		jwk.Kty = "EC"
+		jwk.Crv = public.Curve.Params().Name
+		jwk.X = encode(coordinates[:len(coordinates)/2])
+		jwk.Y = encode(coordinates[len(coordinates)/2:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(public)
+	default:
+		return nil, fmt.Errorf("unsupported public key for signing key %s", key.ID)
+	}
+
+	return jwk, nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,30 @@
+package signingkey
+
+import (
+	"context"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+)
+
+type EmbeddedStore struct {
+	keys *embedded.Collection[Key]
+}
+
+func NewEmbeddedStore(db embedded.DB) *EmbeddedStore {
+	return &EmbeddedStore{keys: embedded.NewCollection[Key](db, "signing_keys")}
+}
+
+func (s *EmbeddedStore) Insert(ctx context.Context, key *Key) error {
+	return s.keys.Put(ctx, key.ID, key)
+}
+
+func (s *EmbeddedStore) List(ctx context.Context) ([]*Key, error) {
+	return s.keys.Find(ctx, embedded.All[Key])
+}
+
+func (s *EmbeddedStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.keys.DeleteWhere(ctx, func(key *Key) bool {
+		return !now.Before(key.ExpiresAt)
+	})
+}
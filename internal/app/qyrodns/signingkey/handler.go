@@ -0,0 +1,44 @@
+package signingkey
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler publishes the public keys admin tokens are verified with, so
+// that other services can verify them. Without a service, as when tokens are
+// signed with an HMAC secret, there are no keys to publish.
+type JWKSHandler struct {
+	router  *gin.Engine
+	service *Service
+}
+
+func NewJWKSHandler(router *gin.Engine, service *Service) *JWKSHandler {
+	return &JWKSHandler{router: router, service: service}
+}
+
+func (h *JWKSHandler) Register() {
+
+	h.router.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		if h.service == nil {
+			c.JSON(http.StatusOK, &JWKSResponse{Keys: []*JWK{}})
+
+			return
+		}
+
+		jwks, err := h.service.JWKS()
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	})
+}
@@ -0,0 +1,19 @@
+package signingkey
+
+import "time"
+
+// Key is a key admin tokens are signed with from ActivatesAt until RetiresAt,
+// and verified with until ExpiresAt, once the last token it signed expired.
+type Key struct {
+	ID          string    `bson:"_id" json:"kid"`
+	Algorithm   string    `bson:"algorithm" json:"alg"`
+	PrivateKey  string    `bson:"private_key" json:"-"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	ActivatesAt time.Time `bson:"activates_at" json:"activates_at"`
+	RetiresAt   time.Time `bson:"retires_at" json:"retires_at"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+func (k *Key) signs(algorithm string, now time.Time) bool {
+	return k.Algorithm == algorithm && !now.Before(k.ActivatesAt) && now.Before(k.RetiresAt)
+}
@@ -0,0 +1,51 @@
+package signingkey
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewMongoStore(mongo *mongo.Collection) *MongoStore {
+	return &MongoStore{mongo: mongo}
+}
+
+func (s *MongoStore) Insert(ctx context.Context, key *Key) error {
+	_, err := s.mongo.InsertOne(ctx, key)
+
+	return err
+}
+
+func (s *MongoStore) List(ctx context.Context) ([]*Key, error) {
+	result, err := s.mongo.Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0)
+
+	err = result.All(ctx, &keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *MongoStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.mongo.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,240 @@
+package signingkey
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Service signs admin tokens with keys of the configured algorithm, replacing
+// the signing key at every rotation interval. Keys are shared through the
+// datastore and cached; the next key is stored ahead of its activation so that
+// every server, and every client of the JWKS, knows it before it is used.
+type Service struct {
+	store              Store
+	algorithm          string
+	rotationInterval   time.Duration
+	verificationPeriod time.Duration
+
+	mu   sync.RWMutex
+	keys []*cachedKey
+}
+
+type cachedKey struct {
+	key  *Key
+	auth *auth.Key
+}
+
+// NewService returns a service for keys of the algorithm that sign tokens for
+// the rotation interval and verify them for the verification period after,
+// which must cover the lifetime of the tokens.
+func NewService(store Store, algorithm string, rotationInterval time.Duration, verificationPeriod time.Duration) (*Service, error) {
+	if !Supported(algorithm) {
+		return nil, fmt.Errorf("signing algorithm %s is not supported", algorithm)
+	}
+
+	if rotationInterval <= 0 {
+		return nil, fmt.Errorf("signing key rotation interval must be positive")
+	}
+
+	if verificationPeriod <= 0 {
+		return nil, fmt.Errorf("signing key verification period must be positive")
+	}
+
+	return &Service{store: store, algorithm: algorithm, rotationInterval: rotationInterval, verificationPeriod: verificationPeriod}, nil
+}
+
+// Rotate creates the current or next signing key when due, deletes the
+// expired keys and refreshes the cache.
+func (s *Service) Rotate(ctx context.Context) error {
+	now := time.Now()
+
+	_, err := s.store.DeleteExpired(ctx, now)
+
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.store.List(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	var current *Key
+	pending := false
+
+	for _, key := range keys {
+		if key.signs(s.algorithm, now) && (current == nil || key.ActivatesAt.After(current.ActivatesAt)) {
+			current = key
+		}
+
+		if key.Algorithm == s.algorithm && key.ActivatesAt.After(now) {
+			pending = true
+		}
+	}
+
+	var created *Key
+
+	switch {
+	case current == nil && !pending:
+		created, err = s.create(ctx, now, now)
+	case current != nil && !pending && current.RetiresAt.Sub(now) <= s.lead():
+		created, err = s.create(ctx, now, current.RetiresAt)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if created != nil {
+		keys = append(keys, created)
+	}
+
+	return s.cache(keys)
+}
+
+// minRotationCheck bounds how often Run checks the keys with tiny rotation
+// intervals.
+const minRotationCheck = time.Second
+
+// Run rotates right away and then often enough to store each key ahead of its
+// activation, until the context is done.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(max(minRotationCheck, min(time.Minute, s.lead()/2)))
+	defer ticker.Stop()
+
+	for {
+		err := s.Rotate(ctx)
+
+		if err != nil && ctx.Err() == nil {
+			log.Printf("error while rotating signing keys: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) SigningKey() (*auth.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	var signing *cachedKey
+
+	for _, cached := range s.keys {
+		if cached.key.signs(s.algorithm, now) && (signing == nil || cached.key.ActivatesAt.After(signing.key.ActivatesAt)) {
+			signing = cached
+		}
+	}
+
+	if signing == nil {
+		return nil, fmt.Errorf("no signing key is available")
+	}
+
+	return signing.auth, nil
+}
+
+func (s *Service) VerificationKey(id string) (*auth.Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+
+	for _, cached := range s.keys {
+		if cached.key.ID == id && now.Before(cached.key.ExpiresAt) {
+			return cached.auth, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %s", id)
+}
+
+// JWKS returns the public keys tokens are verified with, including the next
+// signing key.
+func (s *Service) JWKS() (*JWKSResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	jwks := &JWKSResponse{Keys: make([]*JWK, 0, len(s.keys))}
+
+	for _, cached := range s.keys {
+		if !now.Before(cached.key.ExpiresAt) {
+			continue
+		}
+
+		jwk, err := newJWK(cached.auth)
+
+		if err != nil {
+			return nil, err
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+func (s *Service) create(ctx context.Context, now time.Time, activatesAt time.Time) (*Key, error) {
+	privateKey, err := generate(s.algorithm)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		ID:          primitive.NewObjectID().Hex(),
+		Algorithm:   s.algorithm,
+		PrivateKey:  privateKey,
+		CreatedAt:   now,
+		ActivatesAt: activatesAt,
+		RetiresAt:   activatesAt.Add(s.rotationInterval),
+		ExpiresAt:   activatesAt.Add(s.rotationInterval + s.verificationPeriod),
+	}
+
+	err = s.store.Insert(ctx, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("created %s signing key %s, active from %s", key.Algorithm, key.ID, key.ActivatesAt.Format(time.RFC3339))
+
+	return key, nil
+}
+
+func (s *Service) cache(keys []*Key) error {
+	cached := make([]*cachedKey, 0, len(keys))
+
+	for _, key := range keys {
+		authKey, err := parse(key)
+
+		if err != nil {
+			return err
+		}
+
+		cached = append(cached, &cachedKey{key: key, auth: authKey})
+	}
+
+	s.mu.Lock()
+	s.keys = cached
+	s.mu.Unlock()
+
+	return nil
+}
+
+// lead is how long before the signing key retires its successor is stored.
+func (s *Service) lead() time.Duration {
+	return min(time.Hour, s.rotationInterval/2)
+}
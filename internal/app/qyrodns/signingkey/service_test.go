@@ -0,0 +1,43 @@
+package signingkey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+)
+
+func TestNewServiceRejectsNonPositiveDurations(t *testing.T) {
+	for _, durations := range [][2]time.Duration{
+		{0, time.Minute},
+		{-time.Hour, time.Minute},
+		{time.Hour, 0},
+		{time.Hour, -time.Minute},
+	} {
+		_, err := NewService(nil, "RS256", durations[0], durations[1])
+
+		if err == nil {
+			t.Fatalf("expected a rotation interval of %v and a verification period of %v to be rejected", durations[0], durations[1])
+		}
+	}
+
+	_, err := NewService(nil, "RS256", time.Nanosecond, time.Nanosecond)
+
+	if err != nil {
+		t.Fatalf("expected tiny positive durations to be accepted, got %v", err)
+	}
+}
+
+func TestRunWithTinyRotationInterval(t *testing.T) {
+	service, err := NewService(NewEmbeddedStore(embedded.NewMemoryDB()), "ES256", time.Nanosecond, time.Nanosecond)
+
+	if err != nil {
+		t.Fatalf("error creating service: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	service.Run(ctx)
+}
@@ -0,0 +1,12 @@
+package signingkey
+
+import (
+	"context"
+	"time"
+)
+
+type Store interface {
+	Insert(ctx context.Context, key *Key) error
+	List(ctx context.Context) ([]*Key, error)
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
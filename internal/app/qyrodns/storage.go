@@ -0,0 +1,108 @@
+package qyrodns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/admin"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/apikey"
+	dnsLib "github.com/qyrocloud/qyrodns/internal/app/qyrodns/dns"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/namespace"
+	"github.com/qyrocloud/qyrodns/internal/app/qyrodns/signingkey"
+	"github.com/qyrocloud/qyrodns/internal/pkg/migration"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type stores struct {
+	admins          admin.Store
+	apiKeys         apikey.Store
+	namespaces      namespace.Store
+	apiKeyAccesses  namespace.ApiKeyAccessStore
+	records         dnsLib.RecordStore
+	recordRevisions dnsLib.RecordRevisionStore
+	signingKeys     signingkey.Store
+	migrations      migration.Store
+	transactor      storage.Transactor
+
+	// mongoDatabase is only set on the MongoDB backend.
+	mongoDatabase *mongo.Database
+
+	ping func(ctx context.Context) error
+	// checkTransactions fails when the datastore cannot run transactions.
+	checkTransactions func(ctx context.Context) error
+	close             func() error
+}
+
+func (s *Server) openStores() (*stores, error) {
+	switch storage.Backend(s.config.StorageBackend) {
+	case storage.BackendMongo:
+		return s.openMongoStores()
+	case storage.BackendBolt:
+		db, err := embedded.OpenBolt(s.config.BoltPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return openEmbeddedStores(db), nil
+	case storage.BackendMemory:
+		return openEmbeddedStores(embedded.NewMemoryDB()), nil
+	default:
+		return nil, fmt.Errorf("storage backend %s is not supported", s.config.StorageBackend)
+	}
+}
+
+func (s *Server) openMongoStores() (*stores, error) {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(s.config.MongoEndpoint))
+
+	if err != nil {
+		return nil, err
+	}
+
+	mongoDatabase := client.Database(s.config.MongoDatabase)
+	transactor := storage.NewMongoTransactor(client, s.config.MongoAllowStandalone)
+
+	return &stores{
+		admins:          admin.NewMongoStore(mongoDatabase.Collection("admins")),
+		apiKeys:         apikey.NewMongoStore(mongoDatabase.Collection("api_keys")),
+		namespaces:      namespace.NewMongoStore(mongoDatabase.Collection("namespaces")),
+		apiKeyAccesses:  namespace.NewApiKeyAccessMongoStore(mongoDatabase.Collection("api_key_accesses")),
+		records:         dnsLib.NewRecordMongoStore(mongoDatabase.Collection("records")),
+		recordRevisions: dnsLib.NewRecordRevisionMongoStore(mongoDatabase.Collection("record_revisions")),
+		signingKeys:     signingkey.NewMongoStore(mongoDatabase.Collection("signing_keys")),
+		migrations:      migration.NewMongoStore(mongoDatabase.Collection("schema_migrations")),
+		transactor:      transactor,
+		mongoDatabase:   mongoDatabase,
+		ping: func(ctx context.Context) error {
+			return client.Ping(ctx, nil)
+		},
+		checkTransactions: transactor.Check,
+		close: func() error {
+			return client.Disconnect(context.Background())
+		},
+	}, nil
+}
+
+func openEmbeddedStores(db embedded.DB) *stores {
+	return &stores{
+		admins:          admin.NewEmbeddedStore(db),
+		apiKeys:         apikey.NewEmbeddedStore(db),
+		namespaces:      namespace.NewEmbeddedStore(db),
+		apiKeyAccesses:  namespace.NewApiKeyAccessEmbeddedStore(db),
+		records:         dnsLib.NewRecordEmbeddedStore(db),
+		recordRevisions: dnsLib.NewRecordRevisionEmbeddedStore(db),
+		signingKeys:     signingkey.NewEmbeddedStore(db),
+		migrations:      migration.NewEmbeddedStore(db),
+		transactor:      db,
+		ping: func(ctx context.Context) error {
+			return nil
+		},
+		checkTransactions: func(ctx context.Context) error {
+			return nil
+		},
+		close: db.Close,
+	}
+}
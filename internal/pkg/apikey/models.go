@@ -1,16 +1,55 @@
 package apikey
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ApiKey struct {
-	ID        primitive.ObjectID `bson:"_id" json:"id"`
-	Name      string             `bson:"name" json:"name"`
-	Secret    string             `bson:"secret" json:"-"`
-	CreatorID string             `bson:"creator_id" json:"creator_id"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID   primitive.ObjectID `bson:"_id" json:"id"`
+	Name string             `bson:"name" json:"name"`
+	// SecretHash is the salted hash of the secret, which is only shown when
+	// generated.
+	SecretHash string `bson:"secret_hash" json:"-"`
+	SecretSalt string `bson:"secret_salt" json:"-"`
+	// PreviousSecretHash and PreviousSecretSalt are those of the secret
+	// replaced by a rotation, accepted until PreviousSecretExpiresAt.
+	PreviousSecretHash      string     `bson:"previous_secret_hash,omitempty" json:"-"`
+	PreviousSecretSalt      string     `bson:"previous_secret_salt,omitempty" json:"-"`
+	PreviousSecretExpiresAt *time.Time `bson:"previous_secret_expires_at,omitempty" json:"previous_secret_expires_at,omitempty"`
+	// LegacySecretHash is the hash of a secret generated before secrets were
+	// hashed, accepted until the secret is reset, or until the end of the
+	// overlap once rotated.
+	LegacySecretHash string `bson:"legacy_secret_hash,omitempty" json:"-"`
+	// ExpiresAt is when the API key stops being accepted, if ever.
+	ExpiresAt *time.Time `bson:"expires_at" json:"expires_at"`
+	Disabled  bool       `bson:"disabled" json:"disabled"`
+	// AllowedCIDRs are the networks the API key can be used from, or any
+	// network when empty.
+	AllowedCIDRs []string `bson:"allowed_cidrs,omitempty" json:"allowed_cidrs,omitempty"`
+	// LastUsedAt and LastUsedIP are recorded at most once per
+	// LastUsedInterval, so they can lag behind by as much.
+	LastUsedAt *time.Time `bson:"last_used_at" json:"last_used_at"`
+	LastUsedIP string     `bson:"last_used_ip" json:"last_used_ip,omitempty"`
+	CreatorID  string     `bson:"creator_id" json:"creator_id"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+const LastUsedInterval = time.Minute
+
+// Usable returns why the API key cannot be used at the given time, if it
+// cannot.
+func (k *ApiKey) Usable(now time.Time) error {
+	if k.Disabled {
+		return fmt.Errorf("api key is disabled")
+	}
+
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return fmt.Errorf("api key has expired")
+	}
+
+	return nil
 }
@@ -0,0 +1,60 @@
+package apikey
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// AllowsIP reports whether the API key can be used from the IP. API keys
+// without allowed CIDRs can be used from anywhere.
+func (k *ApiKey) AllowsIP(ip string) bool {
+	if len(k.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(ip)
+
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, cidr := range k.AllowedCIDRs {
+		prefix, err := netip.ParsePrefix(cidr)
+
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NormalizeCIDRs validates the CIDRs, turning single IPs into single-address
+// prefixes and clearing the host bits of the others.
+func NormalizeCIDRs(cidrs []string) ([]string, error) {
+	if cidrs == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid cidr %q", cidr)
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		normalized = append(normalized, prefix.Masked().String())
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,88 @@
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SecretPrefix starts the secrets presented by clients, which are made of the
+// prefix, the ID of the API key and the secret itself: qdns_<id>_<secret>.
+const SecretPrefix = "qdns"
+
+func FormatSecret(id primitive.ObjectID, secret string) string {
+	return fmt.Sprintf("%s_%s_%s", SecretPrefix, id.Hex(), secret)
+}
+
+// ParseSecret returns the ID of the API key and the secret of a presented
+// secret, or false when it is not in the qdns_<id>_<secret> format, like the
+// secrets generated before they were hashed.
+func ParseSecret(presented string) (primitive.ObjectID, string, bool) {
+	prefix, rest, ok := strings.Cut(presented, "_")
+
+	if !ok || prefix != SecretPrefix {
+		return primitive.NilObjectID, "", false
+	}
+
+	hexID, secret, ok := strings.Cut(rest, "_")
+
+	if !ok || secret == "" {
+		return primitive.NilObjectID, "", false
+	}
+
+	id, err := primitive.ObjectIDFromHex(hexID)
+
+	if err != nil {
+		return primitive.NilObjectID, "", false
+	}
+
+	return id, secret, true
+}
+
+func HashSecret(salt string, secret string) string {
+	hash := sha256.Sum256([]byte(salt + secret))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// HashLegacySecret hashes a secret generated before secrets were hashed.
+// These secrets carry no ID to find their salt by, so their hash is unsalted
+// to be looked up; they are long and random enough not to need a salt.
+func HashLegacySecret(secret string) string {
+	return HashSecret("", secret)
+}
+
+// VerifySecret reports whether the secret is the secret of the API key, or
+// its previous secret during the overlap of a rotation.
+func (k *ApiKey) VerifySecret(secret string, now time.Time) bool {
+	if verifySecret(k.SecretHash, k.SecretSalt, secret) {
+		return true
+	}
+
+	return k.InOverlap(now) && verifySecret(k.PreviousSecretHash, k.PreviousSecretSalt, secret)
+}
+
+// AcceptsLegacySecret reports whether the legacy secret of the API key is
+// still valid: until a new secret is generated, or during the overlap of the
+// rotation that replaced it.
+func (k *ApiKey) AcceptsLegacySecret(now time.Time) bool {
+	return k.LegacySecretHash != "" && (k.SecretHash == "" || k.InOverlap(now))
+}
+
+// InOverlap reports whether the previous secret of the API key is accepted.
+func (k *ApiKey) InOverlap(now time.Time) bool {
+	return k.PreviousSecretExpiresAt != nil && now.Before(*k.PreviousSecretExpiresAt)
+}
+
+func verifySecret(hash string, salt string, secret string) bool {
+	if hash == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(HashSecret(salt, secret)), []byte(hash)) == 1
+}
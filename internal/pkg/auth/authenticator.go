@@ -10,19 +10,36 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/qyrocloud/qyrodns/internal/pkg/apikey"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ApiKeyStore is the part of the API key storage the authenticator needs to
+// resolve presented secrets.
+type ApiKeyStore interface {
+	Get(ctx context.Context, id primitive.ObjectID) (*apikey.ApiKey, error)
+	GetByLegacySecretHash(ctx context.Context, hash string) (*apikey.ApiKey, error)
+	SetLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time, ip string) error
+}
+
 type Authenticator struct {
-	jwtSigningKey    []byte
-	issuer           string
-	audience         string
-	apiKeyCollection *mongo.Collection
+	keys           Keys
+	issuer         string
+	audience       string
+	accessTokenTTL time.Duration
+	apiKeyStore    ApiKeyStore
+	usageTracker   *usageTracker
 }
 
-func NewAuthenticator(jwtSigningKey string, issuer string, audience string, apiKeyCollection *mongo.Collection) *Authenticator {
-	return &Authenticator{jwtSigningKey: []byte(jwtSigningKey), issuer: issuer, audience: audience, apiKeyCollection: apiKeyCollection}
+func NewAuthenticator(keys Keys, issuer string, audience string, accessTokenTTL time.Duration, apiKeyStore ApiKeyStore) *Authenticator {
+	return &Authenticator{
+		keys:           keys,
+		issuer:         issuer,
+		audience:       audience,
+		accessTokenTTL: accessTokenTTL,
+		apiKeyStore:    apiKeyStore,
+		usageTracker:   newUsageTracker(apiKeyStore),
+	}
 }
 
 const (
@@ -31,23 +48,42 @@ const (
 	ApiKey         = "ApiKey"
 )
 
-func (a *Authenticator) GenerateAdminToken(adminID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// GenerateAdminToken issues a short-lived access token for the session of the
+// admin. The token is only valid while the admin is at the token version it
+// was issued at and the session lasts.
+func (a *Authenticator) GenerateAdminToken(adminID string, sessionID string, tokenVersion int64) (string, time.Time, error) {
+	key, err := a.keys.SigningKey()
+
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(a.accessTokenTTL)
+
+	token := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+		"jti":  primitive.NewObjectID().Hex(),
 		"sub":  adminID,
+		"sid":  sessionID,
+		"ver":  tokenVersion,
 		"iss":  a.issuer,
 		"aud":  a.audience,
 		"type": TypeAdminToken,
-		"iat":  int(time.Now().Unix()),
-		"exp":  int(time.Now().Add(time.Hour * 24).Unix()),
+		"iat":  int(now.Unix()),
+		"exp":  int(expiresAt.Unix()),
 	})
 
-	tokenString, err := token.SignedString(a.jwtSigningKey)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	tokenString, err := token.SignedString(key.Private)
 
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
 func (a *Authenticator) ValidateApiKeyContext(c *gin.Context, ctx context.Context) (*AuthenticatedApiKey, error) {
@@ -59,33 +95,53 @@ func (a *Authenticator) ValidateApiKeyContext(c *gin.Context, ctx context.Contex
 
 	switch tokenType {
 	case ApiKey:
-		return a.validateApiKey(ctx, token)
+		return a.validateApiKey(ctx, token, c.ClientIP())
 	default:
 		return nil, fmt.Errorf("invalid api key type")
 	}
 }
 
-func (a *Authenticator) validateApiKey(ctx context.Context, apiKey string) (*AuthenticatedApiKey, error) {
-	result := a.apiKeyCollection.FindOne(ctx, bson.M{
-		"secret": apiKey,
-	})
+// validateApiKey finds the API key of the secret by the ID it carries, or by
+// its legacy hash for secrets generated before secrets were hashed, checks it
+// can be used from the given IP and records its use.
+func (a *Authenticator) validateApiKey(ctx context.Context, apiKey string, ip string) (*AuthenticatedApiKey, error) {
+	id, secret, ok := apikey.ParseSecret(apiKey)
+
+	var apiKeyData *apikey.ApiKey
+	var err error
 
-	if errors.Is(result.Err(), mongo.ErrNoDocuments) {
+	if ok {
+		apiKeyData, err = a.apiKeyStore.Get(ctx, id)
+	} else {
+		apiKeyData, err = a.apiKeyStore.GetByLegacySecretHash(ctx, apikey.HashLegacySecret(apiKey))
+	}
+
+	if errors.Is(err, storage.ErrNotFound) {
 		return nil, fmt.Errorf("invalid api key")
 	}
 
-	if result.Err() != nil {
-		return nil, result.Err()
+	if err != nil {
+		return nil, err
 	}
 
-	apiKeyData := &apikey.ApiKey{}
+	now := time.Now()
 
-	err := result.Decode(apiKeyData)
+	if ok && !apiKeyData.VerifySecret(secret, now) || !ok && !apiKeyData.AcceptsLegacySecret(now) {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	err = apiKeyData.Usable(now)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if !apiKeyData.AllowsIP(ip) {
+		return nil, fmt.Errorf("api key is not allowed from %s", ip)
+	}
+
+	a.usageTracker.track(apiKeyData, ip)
+
 	return &AuthenticatedApiKey{ID: apiKeyData.ID.Hex()}, nil
 }
 
@@ -104,48 +160,69 @@ func (a *Authenticator) ValidateAdminContext(c *gin.Context) (*AuthenticatedAdmi
 	}
 }
 
+// validateAdminToken checks the signature, expiry, issuer and audience of the
+// token. Whether it has been revoked is left to the caller, which knows the
+// sessions and token version of the admin.
 func (a *Authenticator) validateAdminToken(tokenString string) (*AuthenticatedAdmin, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return a.jwtSigningKey, nil
-	})
+	claims := jwt.MapClaims{}
+
+	// The token must be signed with the algorithm of the key it names, so
+	// that a public key is never taken for an HMAC secret.
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := a.keys.VerificationKey(kid)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return key.Public, nil
+	}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, keyFunc,
+		jwt.WithIssuer(a.issuer),
+		jwt.WithAudience(a.audience),
+		jwt.WithExpirationRequired(),
+	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		tokenType, ok := claims["type"]
-		if !ok {
-			return nil, fmt.Errorf("invalid token type")
-		}
+	tokenType, ok := claims["type"].(string)
 
-		tokenTypeString, ok := tokenType.(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid token type")
-		}
+	if !ok || tokenType != TypeAdminToken {
+		return nil, fmt.Errorf("invalid token type")
+	}
 
-		if tokenTypeString != TypeAdminToken {
-			return nil, fmt.Errorf("invalid token type")
-		}
+	adminID, ok := claims["sub"].(string)
 
-		id, ok := claims["sub"]
+	if !ok {
+		return nil, fmt.Errorf("invalid access token")
+	}
 
-		if !ok {
-			return nil, fmt.Errorf("invalid access token")
-		}
+	sessionID, ok := claims["sid"].(string)
 
-		adminIDString, ok := id.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid access token")
+	}
 
-		if !ok {
-			return nil, fmt.Errorf("invalid access token")
-		}
+	tokenVersion, ok := claims["ver"].(float64)
 
-		return &AuthenticatedAdmin{
-			ID: adminIDString,
-		}, nil
-	} else {
+	if !ok {
 		return nil, fmt.Errorf("invalid access token")
 	}
+
+	return &AuthenticatedAdmin{
+		ID:           adminID,
+		SessionID:    sessionID,
+		TokenVersion: int64(tokenVersion),
+	}, nil
 }
 
 func (a *Authenticator) extractToken(c *gin.Context) (string, string, error) {
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key signs or verifies admin tokens. Keys with an ID are announced in the
+// kid header of the tokens they sign.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private any
+	Public  any
+}
+
+// Keys provides the keys admin tokens are signed and verified with.
+type Keys interface {
+	// SigningKey returns the key new tokens are signed with.
+	SigningKey() (*Key, error)
+	// VerificationKey returns the key with the ID while the tokens it signed
+	// are accepted.
+	VerificationKey(id string) (*Key, error)
+}
+
+// HMACKeys signs and verifies tokens with a single HS256 secret.
+type HMACKeys struct {
+	key *Key
+}
+
+func NewHMACKeys(secret string) *HMACKeys {
+	return &HMACKeys{key: &Key{Method: jwt.SigningMethodHS256, Private: []byte(secret), Public: []byte(secret)}}
+}
+
+func (k *HMACKeys) SigningKey() (*Key, error) {
+	return k.key, nil
+}
+
+func (k *HMACKeys) VerificationKey(id string) (*Key, error) {
+	if id != "" {
+		return nil, fmt.Errorf("unknown signing key %s", id)
+	}
+
+	return k.key, nil
+}
@@ -1,7 +1,9 @@
 package auth
 
 type AuthenticatedAdmin struct {
-	ID string `json:"id"`
+	ID           string `json:"id"`
+	SessionID    string `json:"session_id"`
+	TokenVersion int64  `json:"token_version"`
 }
 
 type AuthenticatedApiKey struct {
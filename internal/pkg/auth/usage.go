@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// usageTracker records the last use of API keys in the background, at most
+// once per apikey.LastUsedInterval for each key, so that authenticating does
+// not wait for a write.
+type usageTracker struct {
+	store    ApiKeyStore
+	mutex    sync.Mutex
+	recorded map[primitive.ObjectID]time.Time
+	prunedAt time.Time
+}
+
+func newUsageTracker(store ApiKeyStore) *usageTracker {
+	return &usageTracker{store: store, recorded: make(map[primitive.ObjectID]time.Time)}
+}
+
+func (t *usageTracker) track(apiKey *apikey.ApiKey, ip string) {
+	now := time.Now()
+
+	if apiKey.LastUsedAt != nil && apiKey.LastUsedIP == ip && now.Sub(*apiKey.LastUsedAt) < apikey.LastUsedInterval {
+		return
+	}
+
+	t.mutex.Lock()
+
+	if recordedAt, ok := t.recorded[apiKey.ID]; ok && now.Sub(recordedAt) < apikey.LastUsedInterval {
+		t.mutex.Unlock()
+
+		return
+	}
+
+	t.recorded[apiKey.ID] = now
+	t.prune(now)
+	t.mutex.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+
+		err := t.store.SetLastUsed(ctx, apiKey.ID, now, ip)
+
+		if err != nil {
+			log.Printf("error recording the use of api key %s: %v", apiKey.ID.Hex(), err)
+		}
+	}()
+}
+
+// prune forgets, at most once per apikey.LastUsedInterval, the uses recorded
+// longer ago than that, which do not hold back any write anymore, so that keys
+// not used anymore, deleted ones included, do not stay in memory.
+func (t *usageTracker) prune(now time.Time) {
+	if now.Sub(t.prunedAt) < apikey.LastUsedInterval {
+		return
+	}
+
+	t.prunedAt = now
+
+	for id, recordedAt := range t.recorded {
+		if now.Sub(recordedAt) >= apikey.LastUsedInterval {
+			delete(t.recorded, id)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/apikey"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type discardingApiKeyStore struct {
+	ApiKeyStore
+}
+
+func (s *discardingApiKeyStore) SetLastUsed(ctx context.Context, id primitive.ObjectID, at time.Time, ip string) error {
+	return nil
+}
+
+func TestUsageTrackerForgetsOldUses(t *testing.T) {
+	tracker := newUsageTracker(&discardingApiKeyStore{})
+
+	old := primitive.NewObjectID()
+	recent := primitive.NewObjectID()
+
+	tracker.recorded[old] = time.Now().Add(-2 * apikey.LastUsedInterval)
+	tracker.recorded[recent] = time.Now()
+
+	tracker.track(&apikey.ApiKey{ID: primitive.NewObjectID()}, "192.0.2.1")
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if _, ok := tracker.recorded[old]; ok || len(tracker.recorded) != 2 {
+		t.Fatalf("expected only the uses within the interval to be kept, got %v", tracker.recorded)
+	}
+
+	if _, ok := tracker.recorded[recent]; !ok {
+		t.Fatalf("expected the recent use to be kept, got %v", tracker.recorded)
+	}
+}
@@ -1,6 +1,12 @@
 package env
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func GetOrDefault(kety string, defaultValue string) string {
 	value := os.Getenv(kety)
@@ -11,3 +17,53 @@ func GetOrDefault(kety string, defaultValue string) string {
 
 	return value
 }
+
+func GetDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+
+	if err != nil {
+		log.Fatalf("invalid duration %q for %s: %v", value, key, err)
+	}
+
+	return duration
+}
+
+func GetBoolOrDefault(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+
+	if err != nil {
+		log.Fatalf("invalid boolean %q for %s: %v", value, key, err)
+	}
+
+	return parsed
+}
+
+// GetListOrDefault splits the comma-separated value of the key, trimming the
+// spaces around its items.
+func GetListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return defaultValue
+	}
+
+	items := strings.Split(value, ",")
+
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+
+	return items
+}
@@ -0,0 +1,51 @@
+// Package etag maps document versions to HTTP entity tags, for optimistic
+// concurrency control through the If-Match header.
+package etag
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+)
+
+func Format(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// Set sets the ETag header of the response to the version.
+func Set(c *gin.Context, version int64) {
+	c.Header("ETag", Format(version))
+}
+
+// IfMatch returns the version required by the If-Match header of the request,
+// or nil when there is no header or it matches any version.
+func IfMatch(c *gin.Context) (*int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header %s", header)
+	}
+
+	return &version, nil
+}
+
+// Status returns the status code answering a failed change: 412 when the
+// document was not at the expected version, 500 otherwise.
+func Status(err error) int {
+	if errors.Is(err, storage.ErrConflict) {
+		return http.StatusPreconditionFailed
+	}
+
+	return http.StatusInternalServerError
+}
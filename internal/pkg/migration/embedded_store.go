@@ -0,0 +1,24 @@
+package migration
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage/embedded"
+)
+
+type EmbeddedStore struct {
+	migrations *embedded.Collection[Applied]
+}
+
+func NewEmbeddedStore(db embedded.DB) *EmbeddedStore {
+	return &EmbeddedStore{migrations: embedded.NewCollection[Applied](db, "schema_migrations")}
+}
+
+func (s *EmbeddedStore) List(ctx context.Context) ([]*Applied, error) {
+	return s.migrations.Find(ctx, embedded.All[Applied])
+}
+
+func (s *EmbeddedStore) Insert(ctx context.Context, applied *Applied) error {
+	return s.migrations.Put(ctx, strconv.Itoa(applied.Version), applied)
+}
@@ -0,0 +1,100 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Migration moves the database schema or data from the previous version to
+// Version. Migrations should be idempotent: instances starting concurrently
+// may both apply the same migration before one of them records it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context) error
+}
+
+type Applied struct {
+	Version     int       `bson:"_id" json:"version"`
+	Description string    `bson:"description" json:"description"`
+	AppliedAt   time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+type Store interface {
+	List(ctx context.Context) ([]*Applied, error)
+	Insert(ctx context.Context, applied *Applied) error
+}
+
+type Runner struct {
+	store      Store
+	migrations []Migration
+}
+
+func NewRunner(store Store, migrations []Migration) *Runner {
+	return &Runner{store: store, migrations: migrations}
+}
+
+// Run applies, in version order, every migration newer than the current
+// schema version and returns the resulting version.
+func (r *Runner) Run(ctx context.Context) (int, error) {
+	version, err := r.Version(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	migrations := slices.Clone(r.migrations)
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+
+	for _, migration := range migrations {
+		if migration.Version <= version {
+			continue
+		}
+
+		log.Printf("applying migration %d: %s", migration.Version, migration.Description)
+
+		err = migration.Up(ctx)
+
+		if err != nil {
+			return version, fmt.Errorf("error applying migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+
+		err = r.store.Insert(ctx, &Applied{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now(),
+		})
+
+		if err != nil {
+			return version, fmt.Errorf("error recording migration %d: %w", migration.Version, err)
+		}
+
+		version = migration.Version
+	}
+
+	return version, nil
+}
+
+// Version returns the highest applied migration version, 0 for a new
+// database.
+func (r *Runner) Version(ctx context.Context) (int, error) {
+	applied, err := r.store.List(ctx)
+
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+
+	for _, migration := range applied {
+		version = max(version, migration.Version)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,138 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeStore struct {
+	applied []*Applied
+}
+
+func (s *fakeStore) List(ctx context.Context) ([]*Applied, error) {
+	return s.applied, nil
+}
+
+func (s *fakeStore) Insert(ctx context.Context, applied *Applied) error {
+	s.applied = append(s.applied, applied)
+
+	return nil
+}
+
+// recorder returns migrations of the versions that append their version to
+// ran when applied.
+func recorder(ran *[]int, versions ...int) []Migration {
+	migrations := make([]Migration, 0, len(versions))
+
+	for _, version := range versions {
+		migrations = append(migrations, Migration{
+			Version:     version,
+			Description: "test",
+			Up: func(ctx context.Context) error {
+				*ran = append(*ran, version)
+
+				return nil
+			},
+		})
+	}
+
+	return migrations
+}
+
+func TestRunRecordsVersions(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+
+	var ran []int
+
+	version, err := NewRunner(store, recorder(&ran, 2, 1, 3)).Run(ctx)
+
+	if err != nil {
+		t.Fatalf("error running migrations: %v", err)
+	}
+
+	if version != 3 {
+		t.Fatalf("expected version 3, got %d", version)
+	}
+
+	if len(ran) != 3 || ran[0] != 1 || ran[1] != 2 || ran[2] != 3 {
+		t.Fatalf("expected migrations to run in version order, got %v", ran)
+	}
+
+	if len(store.applied) != 3 {
+		t.Fatalf("expected 3 recorded migrations, got %d", len(store.applied))
+	}
+
+	for i, applied := range store.applied {
+		if applied.Version != i+1 || applied.Description != "test" || applied.AppliedAt.IsZero() {
+			t.Fatalf("unexpected recorded migration %+v", applied)
+		}
+	}
+}
+
+func TestRunSkipsAppliedVersions(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{applied: []*Applied{{Version: 1}, {Version: 2}}}
+
+	var ran []int
+
+	version, err := NewRunner(store, recorder(&ran, 1, 2, 3)).Run(ctx)
+
+	if err != nil {
+		t.Fatalf("error running migrations: %v", err)
+	}
+
+	if version != 3 || len(ran) != 1 || ran[0] != 3 {
+		t.Fatalf("expected only migration 3 to run, got version %d after %v", version, ran)
+	}
+
+	ran = nil
+
+	version, err = NewRunner(store, recorder(&ran, 1, 2, 3)).Run(ctx)
+
+	if err != nil {
+		t.Fatalf("error running migrations again: %v", err)
+	}
+
+	if version != 3 || len(ran) != 0 {
+		t.Fatalf("expected no migration to run again, got version %d after %v", version, ran)
+	}
+}
+
+func TestRunStopsAtFailure(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{}
+	failure := errors.New("failure")
+
+	var ran []int
+
+	migrations := recorder(&ran, 1, 3)
+	migrations = append(migrations, Migration{
+		Version:     2,
+		Description: "failing",
+		Up: func(ctx context.Context) error {
+			return failure
+		},
+	})
+
+	version, err := NewRunner(store, migrations).Run(ctx)
+
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected migration failure, got %v", err)
+	}
+
+	if version != 1 || len(ran) != 1 {
+		t.Fatalf("expected to stop at version 1, got version %d after %v", version, ran)
+	}
+
+	current, err := NewRunner(store, migrations).Version(ctx)
+
+	if err != nil {
+		t.Fatalf("error getting version: %v", err)
+	}
+
+	if current != 1 || len(store.applied) != 1 {
+		t.Fatalf("expected the failed migration not to be recorded, got version %d", current)
+	}
+}
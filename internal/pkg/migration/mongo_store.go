@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoStore struct {
+	mongo *mongo.Collection
+}
+
+func NewMongoStore(mongo *mongo.Collection) *MongoStore {
+	return &MongoStore{mongo: mongo}
+}
+
+func (s *MongoStore) List(ctx context.Context) ([]*Applied, error) {
+	result, err := s.mongo.Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]*Applied, 0)
+
+	err = result.All(ctx, &applied)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+func (s *MongoStore) Insert(ctx context.Context, applied *Applied) error {
+	_, err := s.mongo.InsertOne(ctx, applied)
+
+	if mongo.IsDuplicateKeyError(err) {
+		// Recorded by another instance applying the same migration.
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,73 @@
+package pagination
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Find returns the page of the documents of the collection matching the
+// filter, in the given order.
+func Find[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, request *Request, order Order, key func(item *T) Cursor) (*Page[T], error) {
+	total, err := collection.CountDocuments(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	query := filter
+
+	if request.After != nil {
+		query = bson.M{"$and": bson.A{filter, order.after(request.After)}}
+	}
+
+	result, err := collection.Find(ctx, query, options.Find().SetSort(order.sort()).SetLimit(request.Size+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*T, 0)
+
+	err = result.All(ctx, &items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPage(items, request, total, key), nil
+}
+
+func (o Order) sort() bson.D {
+	direction := 1
+
+	if o.Descending {
+		direction = -1
+	}
+
+	if o.Field == "" {
+		return bson.D{{Key: "_id", Value: direction}}
+	}
+
+	return bson.D{{Key: o.Field, Value: direction}, {Key: "_id", Value: 1}}
+}
+
+// after matches the documents following the cursor.
+func (o Order) after(cursor *Cursor) bson.M {
+	operator := "$gt"
+
+	if o.Descending {
+		operator = "$lt"
+	}
+
+	if o.Field == "" {
+		return bson.M{"_id": bson.M{operator: cursor.ID}}
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{o.Field: bson.M{operator: cursor.Value}},
+		bson.M{o.Field: cursor.Value, "_id": bson.M{"$gt": cursor.ID}},
+	}}
+}
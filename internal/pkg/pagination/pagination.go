@@ -0,0 +1,175 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultSize int64 = 50
+	MaxSize     int64 = 500
+)
+
+// Cursor points at the last item of a page. Value is the value of the field
+// the listing is sorted by, if any.
+type Cursor struct {
+	ID    primitive.ObjectID `json:"id"`
+	Value string             `json:"value,omitempty"`
+}
+
+// Request asks for the page following After, or the first page when it is
+// nil.
+type Request struct {
+	After *Cursor
+	Size  int64
+}
+
+// Page is one page of a listing, along with the total number of items of the
+// listing. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []*T   `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      int64  `json:"total"`
+}
+
+// Order is the order of a listing: by Field, then by ID, or only by ID, which
+// is the creation order, when Field is empty. Descending applies to Field when
+// set and to the ID otherwise.
+type Order struct {
+	Field      string
+	Descending bool
+}
+
+// FromQuery reads the cursor and size query parameters.
+func FromQuery(c *gin.Context) (*Request, error) {
+	request := &Request{Size: DefaultSize}
+
+	if size := c.Query("size"); size != "" {
+		sizeInt, err := strconv.ParseInt(size, 10, 64)
+
+		if err != nil || sizeInt < 1 || sizeInt > MaxSize {
+			return nil, fmt.Errorf("size must be between 1 and %d", MaxSize)
+		}
+
+		request.Size = sizeInt
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := Decode(cursor)
+
+		if err != nil {
+			return nil, err
+		}
+
+		request.After = after
+	}
+
+	return request, nil
+}
+
+// Encode returns the opaque form of the cursor handed to clients.
+func Encode(cursor *Cursor) string {
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(cursor string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var decoded Cursor
+
+	err = json.Unmarshal(data, &decoded)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &decoded, nil
+}
+
+// Compare orders the items with the given cursors.
+func (o Order) Compare(a Cursor, b Cursor) int {
+	ids := bytes.Compare(a.ID[:], b.ID[:])
+
+	if o.Field == "" {
+		if o.Descending {
+			return -ids
+		}
+
+		return ids
+	}
+
+	values := strings.Compare(a.Value, b.Value)
+
+	if o.Descending {
+		values = -values
+	}
+
+	if values != 0 {
+		return values
+	}
+
+	return ids
+}
+
+// NewPage returns the page of the items fetched for the request, which hold
+// one more item than the size of the page when there is a next page.
+func NewPage[T any](items []*T, request *Request, total int64, key func(item *T) Cursor) *Page[T] {
+	page := &Page[T]{Items: items, Total: total}
+
+	if int64(len(items)) > request.Size {
+		page.Items = items[:request.Size]
+		last := key(page.Items[len(page.Items)-1])
+		page.NextCursor = Encode(&last)
+	}
+
+	return page
+}
+
+// FromSlice pages all the items of a listing, in any order.
+func FromSlice[T any](items []*T, request *Request, order Order, key func(item *T) Cursor) *Page[T] {
+	slices.SortStableFunc(items, func(a *T, b *T) int {
+		return order.Compare(key(a), key(b))
+	})
+
+	start := 0
+
+	if request.After != nil {
+		start = len(items)
+
+		for i, item := range items {
+			if order.Compare(key(item), *request.After) > 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	end := min(int64(start)+request.Size+1, int64(len(items)))
+
+	return NewPage(items[start:end], request, int64(len(items)), key)
+}
+
+// Map returns the page with its items converted.
+func Map[T any, U any](page *Page[T], convert func(items []*T) ([]*U, error)) (*Page[U], error) {
+	items, err := convert(page.Items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page[U]{Items: items, NextCursor: page.NextCursor, Total: page.Total}, nil
+}
@@ -0,0 +1,121 @@
+package embedded
+
+import (
+	"context"
+	"time"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltTxKey struct{}
+
+type BoltDB struct {
+	db *bolt.DB
+}
+
+func OpenBolt(path string) (*BoltDB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltDB{db: db}, nil
+}
+
+func (b *BoltDB) Get(ctx context.Context, collection string, id string) ([]byte, error) {
+	var data []byte
+
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+
+		if bucket == nil {
+			return storage.ErrNotFound
+		}
+
+		value := bucket.Get([]byte(id))
+
+		if value == nil {
+			return storage.ErrNotFound
+		}
+
+		data = append([]byte(nil), value...)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (b *BoltDB) Put(ctx context.Context, collection string, id string, data []byte) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(collection))
+
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put([]byte(id), data)
+	})
+}
+
+func (b *BoltDB) Delete(ctx context.Context, collection string, id string) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+
+		if bucket == nil || bucket.Get([]byte(id)) == nil {
+			return storage.ErrNotFound
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (b *BoltDB) ForEach(ctx context.Context, collection string, fn func(id string, data []byte) error) error {
+	return b.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
+
+func (b *BoltDB) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(boltTxKey{}).(*bolt.Tx); ok {
+		return fn(ctx)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return fn(context.WithValue(ctx, boltTxKey{}, tx))
+	})
+}
+
+func (b *BoltDB) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltDB) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if tx, ok := ctx.Value(boltTxKey{}).(*bolt.Tx); ok {
+		return fn(tx)
+	}
+
+	return b.db.View(fn)
+}
+
+func (b *BoltDB) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if tx, ok := ctx.Value(boltTxKey{}).(*bolt.Tx); ok {
+		return fn(tx)
+	}
+
+	return b.db.Update(fn)
+}
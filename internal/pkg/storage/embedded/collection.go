@@ -0,0 +1,156 @@
+package embedded
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Collection is a typed view over a collection of an embedded DB. Documents
+// are encoded with their bson tags so that they are stored exactly like they
+// are in MongoDB.
+type Collection[T any] struct {
+	db   DB
+	name string
+}
+
+func NewCollection[T any](db DB, name string) *Collection[T] {
+	return &Collection[T]{db: db, name: name}
+}
+
+func (c *Collection[T]) Get(ctx context.Context, id string) (*T, error) {
+	data, err := c.db.Get(ctx, c.name, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	document := new(T)
+
+	err = bson.Unmarshal(data, document)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+func (c *Collection[T]) Put(ctx context.Context, id string, document *T) error {
+	data, err := bson.Marshal(document)
+
+	if err != nil {
+		return err
+	}
+
+	return c.db.Put(ctx, c.name, id, data)
+}
+
+func (c *Collection[T]) Delete(ctx context.Context, id string) error {
+	return c.db.Delete(ctx, c.name, id)
+}
+
+// Find returns every document for which match returns true, in id order.
+func (c *Collection[T]) Find(ctx context.Context, match func(document *T) bool) ([]*T, error) {
+	documents := make([]*T, 0)
+
+	err := c.scan(ctx, func(id string, document *T) error {
+		if match(document) {
+			documents = append(documents, document)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (c *Collection[T]) Count(ctx context.Context, match func(document *T) bool) (int64, error) {
+	documents, err := c.Find(ctx, match)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(documents)), nil
+}
+
+// DeleteWhere removes every document for which match returns true.
+func (c *Collection[T]) DeleteWhere(ctx context.Context, match func(document *T) bool) (int64, error) {
+	var deleted int64
+
+	err := c.db.WithTransaction(ctx, func(ctx context.Context) error {
+		ids := make([]string, 0)
+
+		err := c.scan(ctx, func(id string, document *T) error {
+			if match(document) {
+				ids = append(ids, id)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			err = c.db.Delete(ctx, c.name, id)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		deleted = int64(len(ids))
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+func (c *Collection[T]) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return c.db.WithTransaction(ctx, fn)
+}
+
+func (c *Collection[T]) scan(ctx context.Context, fn func(id string, document *T) error) error {
+	return c.db.ForEach(ctx, c.name, func(id string, data []byte) error {
+		document := new(T)
+
+		err := bson.Unmarshal(data, document)
+
+		if err != nil {
+			return err
+		}
+
+		return fn(id, document)
+	})
+}
+
+// All matches every document.
+func All[T any](*T) bool {
+	return true
+}
+
+// Page returns the documents of the given zero based page. Like a MongoDB
+// limit, a size of zero means no limit.
+func Page[T any](documents []*T, page int64, size int64) []*T {
+	start := page * size
+
+	if start < 0 || start >= int64(len(documents)) {
+		return make([]*T, 0)
+	}
+
+	if size <= 0 {
+		return documents[start:]
+	}
+
+	end := min(start+size, int64(len(documents)))
+
+	return documents[start:end]
+}
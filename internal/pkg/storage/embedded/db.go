@@ -0,0 +1,20 @@
+package embedded
+
+import (
+	"context"
+)
+
+// DB is an embedded key-value database holding BSON encoded documents grouped
+// in named collections and keyed by id.
+type DB interface {
+	Get(ctx context.Context, collection string, id string) ([]byte, error)
+	Put(ctx context.Context, collection string, id string, data []byte) error
+	Delete(ctx context.Context, collection string, id string) error
+	ForEach(ctx context.Context, collection string, fn func(id string, data []byte) error) error
+
+	// WithTransaction runs fn atomically. Operations performed with the
+	// context passed to fn join the transaction; nested calls reuse it.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	Close() error
+}
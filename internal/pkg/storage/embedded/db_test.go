@@ -0,0 +1,130 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+)
+
+type document struct {
+	ID    string `bson:"_id"`
+	Value string `bson:"value"`
+}
+
+func databases(t *testing.T) map[string]DB {
+	bolt, err := OpenBolt(filepath.Join(t.TempDir(), "test.db"))
+
+	if err != nil {
+		t.Fatalf("error opening bolt database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		bolt.Close()
+	})
+
+	return map[string]DB{
+		"memory": NewMemoryDB(),
+		"bolt":   bolt,
+	}
+}
+
+func TestCollection(t *testing.T) {
+	for name, db := range databases(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			documents := NewCollection[document](db, "documents")
+
+			for _, id := range []string{"b", "a", "c"} {
+				err := documents.Put(ctx, id, &document{ID: id, Value: "value-" + id})
+
+				if err != nil {
+					t.Fatalf("error putting document: %v", err)
+				}
+			}
+
+			found, err := documents.Get(ctx, "a")
+
+			if err != nil || found.Value != "value-a" {
+				t.Fatalf("unexpected document %+v, %v", found, err)
+			}
+
+			all, err := documents.Find(ctx, All[document])
+
+			if err != nil || len(all) != 3 || all[0].ID != "a" || all[2].ID != "c" {
+				t.Fatalf("expected documents in id order, got %+v, %v", all, err)
+			}
+
+			deleted, err := documents.DeleteWhere(ctx, func(d *document) bool {
+				return d.ID != "b"
+			})
+
+			if err != nil || deleted != 2 {
+				t.Fatalf("expected two deleted documents, got %d, %v", deleted, err)
+			}
+
+			_, err = documents.Get(ctx, "a")
+
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Fatalf("expected not found, got %v", err)
+			}
+
+			err = documents.Delete(ctx, "a")
+
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Fatalf("expected not found, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	for name, db := range databases(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			documents := NewCollection[document](db, "documents")
+
+			err := documents.Put(ctx, "a", &document{ID: "a", Value: "before"})
+
+			if err != nil {
+				t.Fatalf("error putting document: %v", err)
+			}
+
+			failure := errors.New("failure")
+
+			err = db.WithTransaction(ctx, func(ctx context.Context) error {
+				err := documents.Put(ctx, "a", &document{ID: "a", Value: "after"})
+
+				if err != nil {
+					return err
+				}
+
+				err = documents.Put(ctx, "b", &document{ID: "b", Value: "new"})
+
+				if err != nil {
+					return err
+				}
+
+				return failure
+			})
+
+			if !errors.Is(err, failure) {
+				t.Fatalf("expected transaction failure, got %v", err)
+			}
+
+			found, err := documents.Get(ctx, "a")
+
+			if err != nil || found.Value != "before" {
+				t.Fatalf("expected rolled back document, got %+v, %v", found, err)
+			}
+
+			_, err = documents.Get(ctx, "b")
+
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Fatalf("expected rolled back insertion, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/qyrocloud/qyrodns/internal/pkg/storage"
+)
+
+var errMemoryDBClosed = errors.New("database is closed")
+
+type memoryTxKey struct{}
+
+type memoryTx struct {
+	undo []func()
+}
+
+// MemoryDB keeps all collections in memory. It is meant for tests and
+// throwaway deployments; everything is lost when the process exits. Once
+// closed, every operation fails, as with the other databases.
+type MemoryDB struct {
+	mu          sync.Mutex
+	closed      bool
+	collections map[string]map[string][]byte
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{collections: make(map[string]map[string][]byte)}
+}
+
+func (m *MemoryDB) Get(ctx context.Context, collection string, id string) ([]byte, error) {
+	var data []byte
+
+	err := m.run(ctx, func(tx *memoryTx) error {
+		value, ok := m.collections[collection][id]
+
+		if !ok {
+			return storage.ErrNotFound
+		}
+
+		data = slices.Clone(value)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (m *MemoryDB) Put(ctx context.Context, collection string, id string, data []byte) error {
+	return m.run(ctx, func(tx *memoryTx) error {
+		documents, ok := m.collections[collection]
+
+		if !ok {
+			documents = make(map[string][]byte)
+			m.collections[collection] = documents
+		}
+
+		previous, existed := documents[id]
+		documents[id] = slices.Clone(data)
+
+		tx.undo = append(tx.undo, func() {
+			if existed {
+				documents[id] = previous
+			} else {
+				delete(documents, id)
+			}
+		})
+
+		return nil
+	})
+}
+
+func (m *MemoryDB) Delete(ctx context.Context, collection string, id string) error {
+	return m.run(ctx, func(tx *memoryTx) error {
+		documents := m.collections[collection]
+		previous, ok := documents[id]
+
+		if !ok {
+			return storage.ErrNotFound
+		}
+
+		delete(documents, id)
+
+		tx.undo = append(tx.undo, func() {
+			documents[id] = previous
+		})
+
+		return nil
+	})
+}
+
+func (m *MemoryDB) ForEach(ctx context.Context, collection string, fn func(id string, data []byte) error) error {
+	return m.run(ctx, func(tx *memoryTx) error {
+		documents := m.collections[collection]
+
+		ids := make([]string, 0, len(documents))
+
+		for id := range documents {
+			ids = append(ids, id)
+		}
+
+		slices.Sort(ids)
+
+		for _, id := range ids {
+			err := fn(id, documents[id])
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (m *MemoryDB) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(ctx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errMemoryDBClosed
+	}
+
+	tx := &memoryTx{}
+
+	err := fn(context.WithValue(ctx, memoryTxKey{}, tx))
+
+	if err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+	}
+
+	return err
+}
+
+func (m *MemoryDB) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+
+	return nil
+}
+
+// run executes fn inside the transaction carried by ctx, or in a transaction
+// of its own when there is none.
+func (m *MemoryDB) run(ctx context.Context, fn func(tx *memoryTx) error) error {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(tx)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errMemoryDBClosed
+	}
+
+	return fn(&memoryTx{})
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrNotFound  = errors.New("document not found")
+	ErrDuplicate = errors.New("duplicate document")
+	// ErrConflict means the document is not at the expected version anymore.
+	ErrConflict = errors.New("document was modified concurrently")
+)
+
+type Backend string
+
+const (
+	BackendMongo  Backend = "mongo"
+	BackendBolt   Backend = "bolt"
+	BackendMemory Backend = "memory"
+)
+
+// VersionFilter matches documents at the given version in MongoDB. Documents
+// written before versions existed have none and are at version 0.
+func VersionFilter(version int64) any {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+
+	return version
+}
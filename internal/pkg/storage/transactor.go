@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs fn atomically. Stores called with the context passed to fn
+// take part in the transaction.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// ErrTransactionsUnsupported means the MongoDB server is a standalone one.
+var ErrTransactionsUnsupported = errors.New("MongoDB transactions require a replica set or a sharded cluster")
+
+// MongoTransactor runs transactions in MongoDB sessions. Transactions need a
+// replica set or a sharded cluster; on a standalone server fn is not run and
+// ErrTransactionsUnsupported is returned, unless allowStandalone is set, in
+// which case fn runs without a transaction.
+type MongoTransactor struct {
+	client          *mongo.Client
+	allowStandalone bool
+
+	mu        sync.Mutex
+	checked   bool
+	supported bool
+}
+
+func NewMongoTransactor(client *mongo.Client, allowStandalone bool) *MongoTransactor {
+	return &MongoTransactor{client: client, allowStandalone: allowStandalone}
+}
+
+func (t *MongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	supported, err := t.transactionsSupported(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if !supported && t.allowStandalone {
+		return fn(ctx)
+	}
+
+	if !supported {
+		return ErrTransactionsUnsupported
+	}
+
+	session, err := t.client.StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (any, error) {
+		return nil, fn(ctx)
+	})
+
+	return err
+}
+
+// Check returns ErrTransactionsUnsupported unless the server supports
+// transactions or standalone servers are allowed, logging a warning then.
+func (t *MongoTransactor) Check(ctx context.Context) error {
+	supported, err := t.transactionsSupported(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if !supported && t.allowStandalone {
+		log.Printf("MongoDB is a standalone server, changes spanning several documents are not atomic")
+		return nil
+	}
+
+	if !supported {
+		return ErrTransactionsUnsupported
+	}
+
+	return nil
+}
+
+func (t *MongoTransactor) transactionsSupported(ctx context.Context) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.checked {
+		return t.supported, nil
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	err := t.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+
+	if err != nil {
+		return false, err
+	}
+
+	t.checked = true
+	t.supported = hello.SetName != "" || hello.Msg == "isdbgrid"
+
+	return t.supported, nil
+}